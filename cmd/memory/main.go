@@ -0,0 +1,153 @@
+// Command memory exports and imports a namespace's memory using the storage
+// configured in cerebellum.yaml.
+//
+//	memory export [-format jsonl|csv|parquet] [-o file] [-start beacon] [-end beacon]
+//	              [-since time] [-until time] [-type a,b] [-task id]
+//	memory import file.jsonl [more.jsonl ...]
+//
+// Exports go to stdout unless -o is given. Imports skip entries that already
+// exist, so the same export can be merged more than once. Prefer the
+// /api/memory/import endpoint while the server is running on file storage.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cerebellum/internal/config"
+	"cerebellum/internal/memory"
+	"cerebellum/internal/sqlstore"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	configPath := flags.String("config", "cerebellum.yaml", "path to cerebellum.yaml")
+	namespace := flags.String("namespace", config.DefaultNamespace, "namespace to read or write")
+
+	switch command {
+	case "export":
+		format := flags.String("format", memory.FormatJSONL, "jsonl, csv or parquet")
+		output := flags.String("o", "", "output file (default stdout)")
+		start := flags.String("start", "", "start beacon (name or name@N)")
+		end := flags.String("end", "", "end beacon (name or name@N)")
+		since := flags.String("since", "", "start time (RFC3339 or Unix seconds)")
+		until := flags.String("until", "", "end time, exclusive (RFC3339 or Unix seconds)")
+		types := flags.String("type", "", "comma-separated entry types")
+		taskID := flags.String("task", "", "task ID")
+		flags.Parse(args)
+
+		search := memory.Search{StartBeacon: *start, EndBeacon: *end, TaskID: *taskID}
+		var err error
+		if search.Since, err = parseTime(*since); err != nil {
+			log.Fatalf("Invalid -since: %v", err)
+		}
+		if search.Until, err = parseTime(*until); err != nil {
+			log.Fatalf("Invalid -until: %v", err)
+		}
+		for _, t := range strings.Split(*types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				search.Types = append(search.Types, t)
+			}
+		}
+		exportMemory(openMemory(*configPath, *namespace), *format, *output, search)
+
+	case "import":
+		flags.Parse(args)
+		if flags.NArg() == 0 {
+			log.Fatalf("No input files")
+		}
+		importMemory(openMemory(*configPath, *namespace), flags.Args())
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: memory export|import [flags] [files]")
+	os.Exit(2)
+}
+
+// openMemory 按配置打开命名空间的记忆存储
+func openMemory(configPath, namespace string) *memory.JSONLMemory {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if !config.ValidNamespace(namespace) {
+		log.Fatalf("Invalid namespace %q", namespace)
+	}
+
+	if cfg.Storage.Backend == "sqlite" {
+		db, err := sqlstore.Open(cfg.Storage.NamespaceSQLitePath(namespace))
+		if err != nil {
+			log.Fatalf("Failed to open sqlite database: %v", err)
+		}
+		return memory.NewWithBackend(db.Memory())
+	}
+
+	mem, err := memory.NewJSONLMemory(cfg.Storage.NamespaceDataDir(namespace))
+	if err != nil {
+		log.Fatalf("Failed to open memory: %v", err)
+	}
+	maxAge, _ := time.ParseDuration(cfg.Storage.MemoryMaxAge)
+	mem.SetRetention(int64(cfg.Storage.MemoryMaxTotalMB)<<20, maxAge)
+	return mem
+}
+
+// exportMemory 导出到文件或标准输出
+func exportMemory(mem *memory.JSONLMemory, format, output string, search memory.Search) {
+	out := os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", output, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	count, err := mem.Export(out, format, search)
+	if err != nil {
+		log.Fatalf("Failed to export memory: %v", err)
+	}
+	log.Printf("✓ Exported %d memory entries", count)
+}
+
+// importMemory 依次导入 JSONL 文件
+func importMemory(mem *memory.JSONLMemory, files []string) {
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", path, err)
+		}
+		result, err := mem.Import(file)
+		file.Close()
+		if err != nil {
+			log.Fatalf("Failed to import %s: %v", path, err)
+		}
+		log.Printf("✓ %s: imported %d of %d entries (%d duplicates, %d invalid lines)",
+			path, result.Imported, result.Total, result.Duplicates, result.Invalid)
+	}
+}
+
+// parseTime 解析 RFC3339 或 Unix 秒，空字符串返回零值
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,129 @@
+// Command migrate imports the file-based ./data store (task snapshots and
+// journal, run history, memory JSONL) into the SQLite database configured in
+// cerebellum.yaml. Each namespace under ./data/namespaces is imported into its
+// own database next to it (namespaces/<name>.db).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"cerebellum/internal/config"
+	"cerebellum/internal/memory"
+	"cerebellum/internal/sqlstore"
+	"cerebellum/internal/task"
+)
+
+func main() {
+	configPath := flag.String("config", "cerebellum.yaml", "path to cerebellum.yaml")
+	dataDir := flag.String("data", "", "file store directory to import (default: storage.data_dir)")
+	dbPath := flag.String("sqlite", "", "target sqlite database (default: storage.sqlite_path)")
+	force := flag.Bool("force", false, "import memory even if the database already has entries")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *dataDir == "" {
+		*dataDir = cfg.Storage.DataDir
+	}
+	if *dbPath == "" {
+		*dbPath = cfg.Storage.SQLitePath
+	}
+
+	// 默认命名空间位于 data 目录，其余命名空间位于 data/namespaces/<name>
+	if err := migrateNamespace(*dataDir, *dbPath, *force); err != nil {
+		log.Fatal(err)
+	}
+	nsDir := filepath.Join(*dataDir, "namespaces")
+	if entries, err := os.ReadDir(nsDir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() || !config.ValidNamespace(entry.Name()) {
+				continue
+			}
+			log.Printf("Namespace %s:", entry.Name())
+			target := filepath.Join(filepath.Dir(*dbPath), "namespaces", entry.Name()+".db")
+			if err := migrateNamespace(filepath.Join(nsDir, entry.Name()), target, *force); err != nil {
+				log.Fatalf("Namespace %s: %v", entry.Name(), err)
+			}
+		}
+	}
+
+	log.Printf("Set storage.backend: \"sqlite\" in %s to use the database", *configPath)
+}
+
+// migrateNamespace 将一个命名空间的文件存储导入 SQLite 数据库
+func migrateNamespace(dataDir, dbPath string, force bool) (err error) {
+	db, err := sqlstore.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	defer func() {
+		if cerr := db.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close sqlite database: %w", cerr)
+		}
+	}()
+
+	// 任务：读取快照并重放变更日志后整体写入
+	fileTasks, err := task.NewFileStore(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open file task store: %w", err)
+	}
+	periodic, once, report, err := fileTasks.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load tasks: %w", err)
+	}
+	for _, problem := range report.SnapshotProblems {
+		log.Printf("Warning: %s", problem)
+	}
+	if err := db.Tasks().Checkpoint(periodic, once); err != nil {
+		return fmt.Errorf("failed to import tasks: %w", err)
+	}
+	log.Printf("✓ Imported %d periodic and %d once tasks", len(periodic), len(once))
+
+	// 执行历史：按时间正序写入
+	runCount := 0
+	for _, tasks := range []map[string]*task.TaskPlan{periodic, once} {
+		for id := range tasks {
+			runs, _, err := fileTasks.ListRuns(id, task.RunQuery{})
+			if err != nil {
+				return fmt.Errorf("failed to read runs for %s: %w", id, err)
+			}
+			for i := len(runs) - 1; i >= 0; i-- {
+				if err := db.Tasks().AppendRun(runs[i]); err != nil {
+					return fmt.Errorf("failed to import run for %s: %w", id, err)
+				}
+				runCount++
+			}
+		}
+	}
+	log.Printf("✓ Imported %d task runs", runCount)
+
+	// 记忆：已有数据时默认跳过，避免重复导入
+	existing, err := db.Memory().Count()
+	if err != nil {
+		return fmt.Errorf("failed to inspect sqlite memory: %w", err)
+	}
+	if existing > 0 && !force {
+		log.Printf("Skipping memory: database already has %d entries (use -force to import anyway)", existing)
+		return nil
+	}
+
+	fileMemory, err := memory.NewFileBackend(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open memory file: %w", err)
+	}
+	entries, err := fileMemory.Query(memory.Query{})
+	if err != nil {
+		return fmt.Errorf("failed to read memory: %w", err)
+	}
+	if err := db.Memory().Import(entries); err != nil {
+		return fmt.Errorf("failed to import memory: %w", err)
+	}
+	log.Printf("✓ Imported %d memory entries", len(entries))
+	return nil
+}
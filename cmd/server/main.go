@@ -60,20 +60,39 @@ func main() {
 	mux.HandleFunc("/health", httpServer.HandleHealth)
 	mux.HandleFunc("/tasks", httpServer.HandleTasks)
 	mux.HandleFunc("/reload", httpServer.HandleReload)
-	mux.HandleFunc("/api/task/", httpServer.HandleAPITaskDelete)
+	mux.HandleFunc("/api/task/", httpServer.HandleAPITask)
 	mux.HandleFunc("/chat", httpServer.HandleChat)
 	mux.HandleFunc("/execute", httpServer.HandleExecute)
 	mux.HandleFunc("/api/chat", httpServer.HandleChat)
 	mux.HandleFunc("/api/execute", httpServer.HandleExecute)
 	mux.HandleFunc("/api/beacon", httpServer.HandleSetBeacon)
-	mux.HandleFunc("/api/memory", httpServer.HandleReadMemory)
+	mux.HandleFunc("/api/memory", httpServer.HandleMemory)
+	mux.HandleFunc("/api/memory/export", httpServer.HandleExportMemory)
+	mux.HandleFunc("/api/memory/import", httpServer.HandleImportMemory)
+	mux.HandleFunc("/api/memory/consolidate", httpServer.HandleConsolidateMemory)
+	mux.HandleFunc("/api/memory/tail", httpServer.HandleTailMemory)
+	mux.HandleFunc("/api/metrics", httpServer.HandleListMetrics)
+	mux.HandleFunc("/api/metrics/series", httpServer.HandleMetricSeries)
 	mux.HandleFunc("/api/beacons", httpServer.HandleListBeacons)
+	mux.HandleFunc("/api/beacons/", httpServer.HandleBeacon)
+	mux.HandleFunc("/api/webhooks/deliveries", httpServer.HandleAPIWebhookDeliveries)
+	mux.HandleFunc("/api/events", httpServer.HandleAPIEvents)
+	mux.HandleFunc("/api/digest", httpServer.HandleAPIDigest)
+	mux.HandleFunc("/api/reports", httpServer.HandleAPIReports)
+	mux.HandleFunc("/api/reports/", httpServer.HandleAPIReports)
+	mux.HandleFunc("/api/decisions", httpServer.HandleAPIDecisions)
+	mux.HandleFunc("/api/decisions/", httpServer.HandleAPIDecisions)
 
 	log.Printf("DEBUG: Mux handlers registered, addr=%s", addr)
 
 	// Start task executor
 	go httpServer.StartTaskExecutor()
 
+	// Start webhook delivery
+	go httpServer.StartNotifier()
+	go httpServer.StartReporter()
+	go httpServer.StartConsolidator()
+
 	// Start HTTP server
 	go func() {
 		log.Printf("DEBUG: Starting ListenAndServe on %s", addr)
@@ -3,6 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,6 +15,19 @@ type Config struct {
 	Server  ServerConfig  `yaml:"server"`
 	Ollama  OllamaConfig  `yaml:"ollama"`
 	Watcher WatcherConfig `yaml:"watcher"`
+	Tasks   TasksConfig   `yaml:"tasks"`
+	Storage StorageConfig `yaml:"storage"`
+	Digest  DigestConfig  `yaml:"digest"`
+	Reports ReportsConfig `yaml:"reports"`
+
+	Consolidation ConsolidationConfig `yaml:"consolidation"`
+
+	Namespaces []NamespaceConfig `yaml:"namespaces"`
+	Webhooks   []WebhookConfig   `yaml:"webhooks"`
+
+	// NamespaceAutoCreate lets a request with an unknown X-Cerebellum-Namespace create that namespace.
+	// Off by default: only "default", configured namespaces and ones already on disk are served.
+	NamespaceAutoCreate bool `yaml:"namespace_auto_create"`
 }
 
 type ServerConfig struct {
@@ -27,6 +44,111 @@ type WatcherConfig struct {
 	PollInterval int `yaml:"poll_interval"` // in milliseconds
 }
 
+type TasksConfig struct {
+	CompletedRetention string         `yaml:"completed_retention"` // e.g. "24h", empty keeps finished once-tasks
+	LeaseTTL           string         `yaml:"lease_ttl"`           // running-task lease, renewed every ttl/3; default 1m
+	RunTimeout         string         `yaml:"run_timeout"`         // a run longer than this is interrupted and requeued; empty means no limit
+	MaxConcurrency     int            `yaml:"max_concurrency"`     // tasks running at once across all owners; default 4
+	OwnerConcurrency   int            `yaml:"owner_concurrency"`   // tasks running at once per owner; default 2
+	OwnerLimits        map[string]int `yaml:"owner_limits"`        // per-owner override of owner_concurrency
+}
+
+// DigestConfig controls the LLM-written digest reports served at /api/digest.
+type DigestConfig struct {
+	MaxTokens int    `yaml:"max_tokens"` // size budget of a digest handed to the brain; default 400
+	Window    string `yaml:"window"`     // period covered when no beacon or since is given; default 24h
+}
+
+// ReportsConfig schedules Markdown reports written to disk, for brains that read files more cheaply than HTTP.
+type ReportsConfig struct {
+	Interval string `yaml:"interval"` // e.g. "1h"; empty disables scheduled reports
+	Dir      string `yaml:"dir"`      // default <data_dir>/reports
+	Keep     int    `yaml:"keep"`     // reports kept per namespace, older ones are deleted; default 48
+	Digest   bool   `yaml:"digest"`   // append the LLM digest of the last interval
+}
+
+// ConsolidationConfig schedules LLM summaries of old memory: hourly summaries of raw entries,
+// then daily summaries of the hourly ones. Raw entries are kept until storage retention removes them.
+type ConsolidationConfig struct {
+	Interval     string   `yaml:"interval"`      // e.g. "1h"; empty disables the background job
+	After        string   `yaml:"after"`         // only periods that ended this long ago are summarized; default 24h
+	Types        []string `yaml:"types"`         // entry types summarized; default task_executed, task_completed, task_failed, task_alert
+	MaxSummaries int      `yaml:"max_summaries"` // summaries written per namespace per run, the rest wait for the next run; default 24
+	MaxTokens    int      `yaml:"max_tokens"`    // length limit of each summary; default 200
+}
+
+// NamespaceDir returns the reports directory of a namespace.
+func (c ReportsConfig) NamespaceDir(name string) string {
+	if name == DefaultNamespace {
+		return c.Dir
+	}
+	return filepath.Join(c.Dir, "namespaces", name)
+}
+
+type StorageConfig struct {
+	Backend    string `yaml:"backend"`     // "file" (JSON/JSONL under data_dir) or "sqlite"
+	DataDir    string `yaml:"data_dir"`    // default ./data
+	SQLitePath string `yaml:"sqlite_path"` // default <data_dir>/cerebellum.db
+
+	MemoryMaxTotalMB  int    `yaml:"memory_max_total_mb"` // rotated memory files are deleted oldest first beyond this total; default 100
+	MemoryMaxAge      string `yaml:"memory_max_age"`      // rotated memory files older than this are deleted; empty keeps them
+	MemoryCompression string `yaml:"memory_compression"`  // gzip (default) or none; rotated memory files are compressed in the background
+}
+
+// WebhookConfig is an outbound target for task change notifications.
+type WebhookConfig struct {
+	Name        string   `yaml:"name"`
+	URL         string   `yaml:"url"`
+	Secret      string   `yaml:"secret"`       // HMAC-SHA256 signing key; empty sends unsigned requests
+	Namespaces  []string `yaml:"namespaces"`   // empty receives every namespace
+	MaxAttempts int      `yaml:"max_attempts"` // default 8
+	Timeout     string   `yaml:"timeout"`      // per request, default 10s
+}
+
+// DefaultNamespace is used by requests without a namespace header or API key.
+const DefaultNamespace = "default"
+
+var namespacePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// NamespaceConfig isolates one brain's tasks, memory and beacons.
+type NamespaceConfig struct {
+	Name           string `yaml:"name"`
+	APIKey         string `yaml:"api_key"`         // if set, requests for this namespace must present it
+	MaxTasks       int    `yaml:"max_tasks"`       // 0 means unlimited
+	MaxConcurrency int    `yaml:"max_concurrency"` // 0 uses tasks.max_concurrency
+}
+
+// ValidNamespace reports whether name can be used as a namespace (and directory) name.
+func ValidNamespace(name string) bool {
+	return namespacePattern.MatchString(name)
+}
+
+// NamespaceDataDir returns the file store directory of a namespace.
+func (c StorageConfig) NamespaceDataDir(name string) string {
+	if name == DefaultNamespace {
+		return c.DataDir
+	}
+	return filepath.Join(c.DataDir, "namespaces", name)
+}
+
+// NamespaceSQLitePath returns the sqlite database of a namespace.
+func (c StorageConfig) NamespaceSQLitePath(name string) string {
+	if name == DefaultNamespace {
+		return c.SQLitePath
+	}
+	return filepath.Join(filepath.Dir(c.SQLitePath), "namespaces", name+".db")
+}
+
+// Namespace returns the configuration of a namespace, if listed.
+func (c *Config) Namespace(name string) (NamespaceConfig, bool) {
+	for _, ns := range c.Namespaces {
+		if ns.Name == name {
+			return ns, true
+		}
+	}
+	return NamespaceConfig{Name: name}, false
+}
+
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -51,6 +173,119 @@ func Load(path string) (*Config, error) {
 	if cfg.Ollama.Host == "" {
 		cfg.Ollama.Host = "http://localhost:11434"
 	}
+	if cfg.Tasks.LeaseTTL == "" {
+		cfg.Tasks.LeaseTTL = "1m"
+	}
+	if _, err := time.ParseDuration(cfg.Tasks.LeaseTTL); err != nil {
+		return nil, fmt.Errorf("invalid tasks.lease_ttl %q", cfg.Tasks.LeaseTTL)
+	}
+	if cfg.Tasks.RunTimeout != "" {
+		if _, err := time.ParseDuration(cfg.Tasks.RunTimeout); err != nil {
+			return nil, fmt.Errorf("invalid tasks.run_timeout %q", cfg.Tasks.RunTimeout)
+		}
+	}
+	if cfg.Tasks.MaxConcurrency <= 0 {
+		cfg.Tasks.MaxConcurrency = 4
+	}
+	if cfg.Tasks.OwnerConcurrency <= 0 {
+		cfg.Tasks.OwnerConcurrency = 2
+	}
+	if cfg.Digest.MaxTokens <= 0 {
+		cfg.Digest.MaxTokens = 400
+	}
+	if cfg.Digest.Window == "" {
+		cfg.Digest.Window = "24h"
+	}
+	if _, err := time.ParseDuration(cfg.Digest.Window); err != nil {
+		return nil, fmt.Errorf("invalid digest.window %q", cfg.Digest.Window)
+	}
+	if cfg.Storage.Backend == "" {
+		cfg.Storage.Backend = "file"
+	}
+	if cfg.Storage.DataDir == "" {
+		cfg.Storage.DataDir = "./data"
+	}
+	if cfg.Storage.SQLitePath == "" {
+		cfg.Storage.SQLitePath = filepath.Join(cfg.Storage.DataDir, "cerebellum.db")
+	}
+	if cfg.Reports.Interval != "" {
+		if d, err := time.ParseDuration(cfg.Reports.Interval); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid reports.interval %q", cfg.Reports.Interval)
+		}
+	}
+	if cfg.Reports.Dir == "" {
+		cfg.Reports.Dir = filepath.Join(cfg.Storage.DataDir, "reports")
+	}
+	if cfg.Reports.Keep <= 0 {
+		cfg.Reports.Keep = 48
+	}
+	if cfg.Consolidation.Interval != "" {
+		if d, err := time.ParseDuration(cfg.Consolidation.Interval); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid consolidation.interval %q", cfg.Consolidation.Interval)
+		}
+	}
+	if cfg.Consolidation.After == "" {
+		cfg.Consolidation.After = "24h"
+	}
+	if d, err := time.ParseDuration(cfg.Consolidation.After); err != nil || d < 0 {
+		return nil, fmt.Errorf("invalid consolidation.after %q", cfg.Consolidation.After)
+	}
+	if cfg.Consolidation.MaxSummaries <= 0 {
+		cfg.Consolidation.MaxSummaries = 24
+	}
+	if cfg.Consolidation.MaxTokens <= 0 {
+		cfg.Consolidation.MaxTokens = 200
+	}
+	if cfg.Storage.MemoryMaxTotalMB <= 0 {
+		cfg.Storage.MemoryMaxTotalMB = 100
+	}
+	if cfg.Storage.MemoryMaxAge != "" {
+		if _, err := time.ParseDuration(cfg.Storage.MemoryMaxAge); err != nil {
+			return nil, fmt.Errorf("invalid storage.memory_max_age %q", cfg.Storage.MemoryMaxAge)
+		}
+	}
+	if cfg.Storage.MemoryCompression == "" {
+		cfg.Storage.MemoryCompression = "gzip"
+	}
+	if cfg.Storage.MemoryCompression != "gzip" && cfg.Storage.MemoryCompression != "none" {
+		return nil, fmt.Errorf("unknown storage.memory_compression %q (want gzip or none)", cfg.Storage.MemoryCompression)
+	}
+	if cfg.Storage.Backend != "file" && cfg.Storage.Backend != "sqlite" {
+		return nil, fmt.Errorf("unknown storage backend %q (want file or sqlite)", cfg.Storage.Backend)
+	}
+	keys := make(map[string]string)
+	for _, ns := range cfg.Namespaces {
+		if !ValidNamespace(ns.Name) {
+			return nil, fmt.Errorf("invalid namespace name %q", ns.Name)
+		}
+		if ns.APIKey == "" {
+			continue
+		}
+		if other, dup := keys[ns.APIKey]; dup {
+			return nil, fmt.Errorf("namespaces %q and %q share an api_key", other, ns.Name)
+		}
+		keys[ns.APIKey] = ns.Name
+	}
+	webhooks := make(map[string]bool)
+	for i := range cfg.Webhooks {
+		wh := &cfg.Webhooks[i]
+		if !namespacePattern.MatchString(wh.Name) || webhooks[wh.Name] {
+			return nil, fmt.Errorf("invalid or duplicate webhook name %q", wh.Name)
+		}
+		webhooks[wh.Name] = true
+		if !strings.HasPrefix(wh.URL, "http://") && !strings.HasPrefix(wh.URL, "https://") {
+			return nil, fmt.Errorf("webhook %s: url must be http or https", wh.Name)
+		}
+		if wh.MaxAttempts <= 0 {
+			wh.MaxAttempts = 8
+		}
+		if wh.Timeout == "" {
+			wh.Timeout = "10s"
+		}
+		if _, err := time.ParseDuration(wh.Timeout); err != nil {
+			return nil, fmt.Errorf("webhook %s: invalid timeout %q", wh.Name, wh.Timeout)
+		}
+	}
 
 	return &cfg, nil
 }
@@ -0,0 +1,106 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cerebellum/internal/memory"
+)
+
+// 记忆整合提示词的参数
+const (
+	summaryEntryRunes   = 160 // 每条原始记忆的截断长度
+	summaryPerTaskLimit = 6   // 每个任务最多展开的条目数，其余只计数
+)
+
+// SummaryPrompt 构造记忆整合的提示词
+//
+// level 为小时时 entries 是原始记忆，按任务分组后只展开开头和结尾的若干条；
+// 为天时 entries 是当天的小时摘要。超出 maxPromptTokens 的部分只计数不展开。
+func SummaryPrompt(level string, start, end time.Time, entries []memory.MemoryEntry, maxTokens int) string {
+	var b strings.Builder
+	b.WriteString("你是小脑，负责把一段时间的记忆压缩成摘要，供大脑在粗粒度回顾时代替原始记录。\n")
+	period := "1 小时"
+	if level == memory.LevelDay {
+		period = "1 天"
+	}
+	fmt.Fprintf(&b, "时间范围：%s 至 %s（%s），共 %d 条记录。\n\n",
+		start.Format(time.RFC3339), end.Format(time.RFC3339), period, len(entries))
+
+	used := EstimateTokens(b.String())
+	if level == memory.LevelDay {
+		b.WriteString("各小时摘要：\n")
+		for i, entry := range entries {
+			line := fmt.Sprintf("- %s %s\n", memory.SummaryStart(entry).Format("15:04"), oneLine(entry.Content))
+			if used+EstimateTokens(line) > maxPromptTokens {
+				fmt.Fprintf(&b, "- ……另有 %d 个小时省略\n", len(entries)-i)
+				break
+			}
+			used += EstimateTokens(line)
+			b.WriteString(line)
+		}
+	} else {
+		b.WriteString("记录（按任务分组）：\n")
+		for _, group := range groupEntries(entries) {
+			header := fmt.Sprintf("[%s] %d 条\n", group.task, len(group.entries))
+			if used+EstimateTokens(header) > maxPromptTokens {
+				b.WriteString("……其余任务省略\n")
+				break
+			}
+			used += EstimateTokens(header)
+			b.WriteString(header)
+			for i, entry := range sampleEntries(group.entries, summaryPerTaskLimit) {
+				line := fmt.Sprintf("- %s %s：%s\n", entry.Timestamp.Format("15:04:05"), entry.Type,
+					truncate(oneLine(entry.Content), summaryEntryRunes))
+				if used+EstimateTokens(line) > maxPromptTokens {
+					fmt.Fprintf(&b, "- ……另有 %d 条省略\n", len(group.entries)-i)
+					break
+				}
+				used += EstimateTokens(line)
+				b.WriteString(line)
+			}
+		}
+	}
+
+	b.WriteString("\n用一段话概括这段时间发生了什么：数值的变化趋势和范围、失败与告警、值得注意的变化。")
+	b.WriteString("不要逐条复述，不要输出标题或列表。\n")
+	fmt.Fprintf(&b, "长度不超过 %d tokens。\n", maxTokens)
+	return b.String()
+}
+
+// entryGroup 一个任务在时段内的记忆
+type entryGroup struct {
+	task    string
+	entries []memory.MemoryEntry
+}
+
+// groupEntries 按任务分组，保持任务首次出现的顺序
+func groupEntries(entries []memory.MemoryEntry) []*entryGroup {
+	var groups []*entryGroup
+	byTask := make(map[string]*entryGroup)
+	for _, entry := range entries {
+		task := entry.TaskID
+		if task == "" {
+			task = "-"
+		}
+		g := byTask[task]
+		if g == nil {
+			g = &entryGroup{task: task}
+			byTask[task] = g
+			groups = append(groups, g)
+		}
+		g.entries = append(g.entries, entry)
+	}
+	return groups
+}
+
+// sampleEntries 取开头和结尾共 n 条，中间的变化由数量和首尾对比体现
+func sampleEntries(entries []memory.MemoryEntry, n int) []memory.MemoryEntry {
+	if len(entries) <= n {
+		return entries
+	}
+	head := n / 2
+	sampled := append([]memory.MemoryEntry(nil), entries[:head]...)
+	return append(sampled, entries[len(entries)-(n-head):]...)
+}
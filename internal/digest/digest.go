@@ -0,0 +1,437 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 摘要来源
+const (
+	SourceLLM   = "llm"   // 本地模型生成
+	SourceRules = "rules" // 模型不可用或输出无法解析时按规则生成
+)
+
+// 输入压缩参数：本地模型上下文有限，只给出每个任务的汇总和片段
+const (
+	maxPromptTokens = 3000
+	snippetRunes    = 200
+	fallbackRunes   = 80
+	minItemRunes    = 20
+)
+
+// Run 参与摘要的一次执行
+type Run struct {
+	TaskID     string
+	Command    string
+	Status     string
+	StartedAt  time.Time
+	DurationMs int64
+	Result     string
+	Error      string
+}
+
+// Alert 参与摘要的一条告警
+type Alert struct {
+	TaskID  string
+	Time    time.Time
+	Content string
+}
+
+// Input 摘要的原始数据
+type Input struct {
+	Namespace string
+	Since     time.Time
+	Until     time.Time
+	Runs      []Run
+	Alerts    []Alert
+}
+
+// Stats 时间范围内的执行统计
+type Stats struct {
+	Tasks     int `json:"tasks"`
+	Runs      int `json:"runs"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Timeout   int `json:"timeout"`
+	Cancelled int `json:"cancelled"`
+	Alerts    int `json:"alerts"`
+}
+
+// Report 给大脑的精简报告
+type Report struct {
+	Namespace       string    `json:"namespace"`
+	Since           time.Time `json:"since"`
+	Until           time.Time `json:"until"`
+	GeneratedAt     time.Time `json:"generated_at"`
+	Stats           Stats     `json:"stats"`
+	Highlights      []string  `json:"highlights"`
+	Anomalies       []string  `json:"anomalies"`
+	Recommendations []string  `json:"recommendations"`
+	Text            string    `json:"text"` // 【监控报告】格式的全文
+	Source          string    `json:"source"`
+	Model           string    `json:"model,omitempty"`
+	LLMError        string    `json:"llm_error,omitempty"`
+	Tokens          int       `json:"tokens"` // Text 的估算 token 数
+	Budget          int       `json:"budget"`
+	Truncated       bool      `json:"truncated,omitempty"`
+}
+
+// GenerateFunc 调用本地模型，maxTokens 限制生成长度
+type GenerateFunc func(ctx context.Context, prompt string, maxTokens int) (string, error)
+
+// taskGroup 单个任务在时间范围内的执行汇总
+type taskGroup struct {
+	id       string
+	command  string
+	runs     int
+	status   map[string]int
+	last     string
+	errors   []string
+	maxMs    int64
+	firstIdx int
+}
+
+// Build 生成摘要：先让模型按【监控报告】格式归纳，失败时退回规则摘要，最后裁剪到 budget
+func Build(ctx context.Context, generate GenerateFunc, in Input, budget int) *Report {
+	groups, stats := summarize(in)
+	report := &Report{
+		Namespace:   in.Namespace,
+		Since:       in.Since,
+		Until:       in.Until,
+		GeneratedAt: time.Now(),
+		Stats:       stats,
+		Budget:      budget,
+	}
+
+	if stats.Runs == 0 && stats.Alerts == 0 {
+		report.Source = SourceRules
+		report.Anomalies = []string{}
+		report.Highlights = []string{"时间范围内没有执行记录或告警"}
+		report.Recommendations = []string{"无需立即行动"}
+		fit(report, budget)
+		return report
+	}
+
+	// 模型输出是 JSON，留出结构开销的余量，最终长度由 fit 保证
+	output, err := generate(ctx, buildPrompt(in, groups, stats, budget), budget+budget/2)
+	if err == nil {
+		err = parseOutput(output, report)
+	}
+	if err != nil {
+		report.LLMError = err.Error()
+		applyRules(report, in, groups)
+	} else {
+		report.Source = SourceLLM
+	}
+
+	fit(report, budget)
+	return report
+}
+
+// summarize 按任务汇总执行记录，有失败的任务排在前面，其次按执行次数
+func summarize(in Input) ([]*taskGroup, Stats) {
+	stats := Stats{Runs: len(in.Runs), Alerts: len(in.Alerts)}
+	byID := make(map[string]*taskGroup)
+	var groups []*taskGroup
+
+	for i, run := range in.Runs {
+		g, ok := byID[run.TaskID]
+		if !ok {
+			g = &taskGroup{id: run.TaskID, command: run.Command, status: make(map[string]int), firstIdx: i}
+			byID[run.TaskID] = g
+			groups = append(groups, g)
+		}
+		g.runs++
+		g.status[run.Status]++
+		if run.DurationMs > g.maxMs {
+			g.maxMs = run.DurationMs
+		}
+		if run.Result != "" {
+			g.last = run.Result
+		}
+		if run.Error != "" && len(g.errors) < 3 && !contains(g.errors, run.Error) {
+			g.errors = append(g.errors, run.Error)
+		}
+
+		switch run.Status {
+		case "completed":
+			stats.Completed++
+		case "failed":
+			stats.Failed++
+		case "timeout":
+			stats.Timeout++
+		case "cancelled":
+			stats.Cancelled++
+		}
+	}
+	stats.Tasks = len(groups)
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		fi, fj := groups[i].problems(), groups[j].problems()
+		if (fi > 0) != (fj > 0) {
+			return fi > 0
+		}
+		if groups[i].runs != groups[j].runs {
+			return groups[i].runs > groups[j].runs
+		}
+		return groups[i].firstIdx < groups[j].firstIdx
+	})
+	return groups, stats
+}
+
+// problems 失败与超时的次数
+func (g *taskGroup) problems() int {
+	return g.status["failed"] + g.status["timeout"]
+}
+
+// buildPrompt 构造摘要提示词，超出 maxPromptTokens 的任务只计数不展开
+func buildPrompt(in Input, groups []*taskGroup, stats Stats, budget int) string {
+	var b strings.Builder
+	b.WriteString("你是小脑，负责把任务执行记录压缩成给大脑的简报，帮助大脑节省 token。\n")
+	fmt.Fprintf(&b, "时间范围：%s 至 %s，共 %d 个任务、%d 次执行（成功 %d，失败 %d，超时 %d，取消 %d），%d 条告警。\n\n",
+		in.Since.Format(time.RFC3339), in.Until.Format(time.RFC3339),
+		stats.Tasks, stats.Runs, stats.Completed, stats.Failed, stats.Timeout, stats.Cancelled, stats.Alerts)
+
+	used := EstimateTokens(b.String())
+	b.WriteString("执行记录（按任务汇总）：\n")
+	for i, g := range groups {
+		line := fmt.Sprintf("- [%s] 命令：%s；执行 %d 次（%s）；最长 %dms",
+			g.id, truncate(g.command, snippetRunes), g.runs, formatStatus(g.status), g.maxMs)
+		if g.last != "" {
+			line += "；最近结果：" + truncate(oneLine(g.last), snippetRunes)
+		}
+		if len(g.errors) > 0 {
+			line += "；错误：" + truncate(oneLine(strings.Join(g.errors, " | ")), snippetRunes)
+		}
+		line += "\n"
+		if used+EstimateTokens(line) > maxPromptTokens {
+			fmt.Fprintf(&b, "- ……另有 %d 个任务省略\n", len(groups)-i)
+			break
+		}
+		used += EstimateTokens(line)
+		b.WriteString(line)
+	}
+
+	if len(in.Alerts) > 0 {
+		b.WriteString("\n告警：\n")
+		for i, alert := range in.Alerts {
+			line := fmt.Sprintf("- %s [%s] %s\n", alert.Time.Format("01-02 15:04"), alert.TaskID, truncate(oneLine(alert.Content), snippetRunes))
+			if used+EstimateTokens(line) > maxPromptTokens {
+				fmt.Fprintf(&b, "- ……另有 %d 条告警省略\n", len(in.Alerts)-i)
+				break
+			}
+			used += EstimateTokens(line)
+			b.WriteString(line)
+		}
+	}
+
+	b.WriteString("\n只输出一个 JSON 对象，不要输出其他文字：\n")
+	b.WriteString(`{"highlights": ["..."], "anomalies": ["..."], "recommendations": ["..."]}` + "\n")
+	b.WriteString("highlights：值得大脑知道的重要发现；anomalies：失败、超时、告警等异常（没有则为空数组）；")
+	b.WriteString("recommendations：建议大脑做出的决策，没有需要决策的内容时写“无需立即行动”。\n")
+	fmt.Fprintf(&b, "每条一句话，总长度不超过 %d tokens。\n", budget)
+	return b.String()
+}
+
+// parseOutput 从模型输出中取出 JSON 对象
+func parseOutput(output string, report *Report) error {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end <= start {
+		return fmt.Errorf("model output is not a JSON object")
+	}
+
+	var parsed struct {
+		Highlights      []string `json:"highlights"`
+		Anomalies       []string `json:"anomalies"`
+		Recommendations []string `json:"recommendations"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &parsed); err != nil {
+		return fmt.Errorf("failed to parse model output: %w", err)
+	}
+
+	report.Highlights = cleanItems(parsed.Highlights)
+	report.Anomalies = cleanItems(parsed.Anomalies)
+	report.Recommendations = cleanItems(parsed.Recommendations)
+	if len(report.Highlights) == 0 && len(report.Anomalies) == 0 && len(report.Recommendations) == 0 {
+		return fmt.Errorf("model output has no findings")
+	}
+	return nil
+}
+
+// applyRules 不依赖模型的规则摘要
+func applyRules(report *Report, in Input, groups []*taskGroup) {
+	report.Source = SourceRules
+	report.Highlights = []string{}
+	report.Anomalies = []string{}
+	report.Recommendations = []string{}
+
+	var failedIDs, alertIDs []string
+	for _, g := range groups {
+		item := fmt.Sprintf("%s：执行 %d 次（%s）", g.id, g.runs, formatStatus(g.status))
+		if g.last != "" {
+			item += "，最近结果：" + truncate(oneLine(g.last), fallbackRunes)
+		}
+		report.Highlights = append(report.Highlights, item)
+
+		if g.problems() > 0 {
+			failedIDs = append(failedIDs, g.id)
+			item := fmt.Sprintf("%s：失败 %d 次，超时 %d 次", g.id, g.status["failed"], g.status["timeout"])
+			if len(g.errors) > 0 {
+				item += "，错误：" + truncate(oneLine(g.errors[0]), fallbackRunes)
+			}
+			report.Anomalies = append(report.Anomalies, item)
+		}
+	}
+	for _, alert := range in.Alerts {
+		report.Anomalies = append(report.Anomalies, fmt.Sprintf("告警 %s：%s", alert.TaskID, truncate(oneLine(alert.Content), fallbackRunes)))
+		if !contains(alertIDs, alert.TaskID) {
+			alertIDs = append(alertIDs, alert.TaskID)
+		}
+	}
+
+	if len(alertIDs) > 0 {
+		report.Recommendations = append(report.Recommendations, "处理告警："+strings.Join(alertIDs, "、"))
+	}
+	if len(failedIDs) > 0 {
+		report.Recommendations = append(report.Recommendations, "检查失败或超时的任务："+strings.Join(failedIDs, "、"))
+	}
+	if len(report.Recommendations) == 0 {
+		report.Recommendations = []string{"无需立即行动：均为常规执行"}
+	}
+}
+
+// fit 渲染报告并裁剪到 budget：依次减少发现、异常、建议的条数，最后截短单条
+func fit(report *Report, budget int) {
+	for {
+		report.Text = render(report)
+		report.Tokens = EstimateTokens(report.Text)
+		if budget <= 0 || report.Tokens <= budget {
+			return
+		}
+		if !shrink(report) {
+			return
+		}
+		report.Truncated = true
+	}
+}
+
+// shrink 去掉一条或截短一条，无法再缩小时返回 false
+func shrink(report *Report) bool {
+	for _, list := range []*[]string{&report.Highlights, &report.Anomalies, &report.Recommendations} {
+		if len(*list) > 1 {
+			*list = (*list)[:len(*list)-1]
+			return true
+		}
+	}
+
+	var longest *string
+	for _, list := range [][]string{report.Highlights, report.Anomalies, report.Recommendations} {
+		for i := range list {
+			if longest == nil || utf8.RuneCountInString(list[i]) > utf8.RuneCountInString(*longest) {
+				longest = &list[i]
+			}
+		}
+	}
+	if longest == nil || utf8.RuneCountInString(*longest) <= minItemRunes {
+		return false
+	}
+	*longest = truncate(*longest, utf8.RuneCountInString(*longest)/2)
+	return true
+}
+
+// render 按 BRAIN-CEREBELLUM-PROTOCOL.md 的【监控报告】格式输出
+func render(report *Report) string {
+	var b strings.Builder
+	b.WriteString("【监控报告】\n")
+	fmt.Fprintf(&b, "时间：%s\n", report.GeneratedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "任务：%s 共 %d 个任务，%d 次执行\n", report.Namespace, report.Stats.Tasks, report.Stats.Runs)
+	fmt.Fprintf(&b, "范围：%s ~ %s\n", report.Since.Format("01-02 15:04"), report.Until.Format("01-02 15:04"))
+
+	if len(report.Highlights) > 0 {
+		b.WriteString("\n发现：\n")
+		for i, item := range report.Highlights {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, item)
+		}
+	}
+	if len(report.Anomalies) > 0 {
+		b.WriteString("\n异常：\n")
+		for i, item := range report.Anomalies {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, item)
+		}
+	}
+	if len(report.Recommendations) > 0 {
+		b.WriteString("\n建议：\n")
+		for _, item := range report.Recommendations {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+	}
+
+	b.WriteString("\n等待大脑决策...\n")
+	return b.String()
+}
+
+// EstimateTokens 粗略估算 token 数：ASCII 约 4 个字符一个 token，其他字符（如中文）各算一个
+func EstimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// formatStatus 将状态计数格式化为 "completed 3, failed 1"
+func formatStatus(status map[string]int) string {
+	keys := make([]string, 0, len(status))
+	for k := range status {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s %d", k, status[k]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// truncate 截断到 n 个字符
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// oneLine 将多行文本合并为一行
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// cleanItems 去掉空白条目
+func cleanItems(items []string) []string {
+	cleaned := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			cleaned = append(cleaned, item)
+		}
+	}
+	return cleaned
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
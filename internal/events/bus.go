@@ -0,0 +1,174 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeTaskChange = "task_change" // 任务状态变化（TaskChange）
+	TypeAlert      = "alert"       // 告警条件满足
+	TypeMemory     = "memory"      // 写入记忆
+	TypeBeacon     = "beacon"      // 设置、删除或重命名信标
+	TypeDecision   = "decision"    // 任务请求大脑决策
+)
+
+// Event 事件流中的一条事件
+type Event struct {
+	Cursor string      `json:"cursor"`
+	Type   string      `json:"type"`
+	Time   time.Time   `json:"time"`
+	TaskID string      `json:"task_id,omitempty"`
+	Data   interface{} `json:"data"`
+
+	seq uint64
+}
+
+// Bus 保存最近事件的环形缓冲区，支持按游标读取和等待新事件
+//
+// 游标格式为 "<epoch>-<seq>"。epoch 在每次进程启动时变化，
+// 重启前的游标或已被覆盖的游标在读取时会标记 gap，表示中间有事件丢失。
+type Bus struct {
+	epoch   string
+	size    int
+	events  []Event // 按 seq 递增，最多 size 条
+	nextSeq uint64
+	notify  chan struct{}
+	mu      sync.Mutex
+}
+
+// NewBus 创建事件总线，size 为保留的最近事件数
+func NewBus(size int) *Bus {
+	return &Bus{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:    size,
+		nextSeq: 1,
+		notify:  make(chan struct{}),
+	}
+}
+
+// Publish 追加事件并唤醒所有等待者
+func (b *Bus) Publish(eventType, taskID string, data interface{}) {
+	b.mu.Lock()
+	seq := b.nextSeq
+	b.nextSeq++
+	b.events = append(b.events, Event{
+		Cursor: b.cursor(seq),
+		Type:   eventType,
+		Time:   time.Now(),
+		TaskID: taskID,
+		Data:   data,
+		seq:    seq,
+	})
+	if len(b.events) > b.size {
+		b.events = append([]Event(nil), b.events[len(b.events)-b.size:]...)
+	}
+	close(b.notify)
+	b.notify = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// cursor 格式化游标
+func (b *Bus) cursor(seq uint64) string {
+	return fmt.Sprintf("%s-%d", b.epoch, seq)
+}
+
+// Latest 返回最新事件的游标，用于只接收之后的事件
+func (b *Bus) Latest() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cursor(b.nextSeq - 1)
+}
+
+// Read 返回游标之后的事件
+//
+// types 为空时不过滤；next 是下次读取应使用的游标；gap 表示游标之后
+// 有事件已不在缓冲区中（游标过旧或来自重启前的进程）。
+func (b *Bus) Read(cursor string, types map[string]bool, limit int) (events []Event, next string, gap bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	after, gap, err := b.parseCursor(cursor)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if len(b.events) > 0 && b.events[0].seq > after+1 {
+		gap = true
+	}
+
+	next = b.cursor(after)
+	for _, e := range b.events {
+		if e.seq <= after {
+			continue
+		}
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+		next = e.Cursor
+		if len(types) > 0 && !types[e.Type] {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, next, gap, nil
+}
+
+// parseCursor 解析游标，返回其后应读取的起点
+func (b *Bus) parseCursor(cursor string) (uint64, bool, error) {
+	if cursor == "" {
+		return b.nextSeq - 1, false, nil
+	}
+	if cursor == "0" {
+		return 0, false, nil
+	}
+	epoch, raw, found := strings.Cut(cursor, "-")
+	if !found {
+		return 0, false, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	seq, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	if epoch != b.epoch {
+		// 进程重启前的游标：从头读取当前缓冲区并提示可能有遗漏
+		return 0, true, nil
+	}
+	if seq >= b.nextSeq {
+		return b.nextSeq - 1, false, nil
+	}
+	return seq, false, nil
+}
+
+// Wait 阻塞到游标之后有新事件、ctx 结束或超时，返回是否有新事件
+func (b *Bus) Wait(ctx context.Context, cursor string, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		b.mu.Lock()
+		after, _, err := b.parseCursor(cursor)
+		if err != nil {
+			b.mu.Unlock()
+			return false
+		}
+		if b.nextSeq-1 > after {
+			b.mu.Unlock()
+			return true
+		}
+		notify := b.notify
+		b.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,48 +30,93 @@ func NewOllama(host, model string) *OllamaClient {
 
 // Generate sends a prompt to Ollama and returns the response
 func (c *OllamaClient) Generate(prompt string) (string, error) {
+	return c.GenerateContext(context.Background(), prompt)
+}
+
+// GenerateContext is like Generate but aborts the request when ctx is cancelled
+func (c *OllamaClient) GenerateContext(ctx context.Context, prompt string) (string, error) {
+	result, err := c.GenerateWithUsage(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return result.Response, nil
+}
+
+// GenerateResult holds a completed generation together with its token usage
+type GenerateResult struct {
+	Response         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// GenerateWithUsage sends a prompt to Ollama and returns the response with token counts
+func (c *OllamaClient) GenerateWithUsage(ctx context.Context, prompt string) (*GenerateResult, error) {
+	return c.GenerateLimited(ctx, prompt, 0)
+}
+
+// GenerateLimited is like GenerateWithUsage but stops after maxTokens generated tokens (0 means no limit)
+func (c *OllamaClient) GenerateLimited(ctx context.Context, prompt string, maxTokens int) (*GenerateResult, error) {
 	reqBody := map[string]interface{}{
 		"model":  c.model,
 		"prompt": prompt,
 		"stream": false,
 	}
+	if maxTokens > 0 {
+		reqBody["options"] = map[string]interface{}{"num_predict": maxTokens}
+	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.host+"/api/generate", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.host+"/api/generate", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ollama returned error: %s", string(body))
+		return nil, fmt.Errorf("ollama returned error: %s", string(body))
 	}
 
-	var result map[string]interface{}
+	var result struct {
+		Model           string  `json:"model"`
+		Response        *string `json:"response"`
+		PromptEvalCount int     `json:"prompt_eval_count"`
+		EvalCount       int     `json:"eval_count"`
+	}
 	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if result.Response == nil {
+		return nil, fmt.Errorf("no response in result")
 	}
 
-	if response, ok := result["response"].(string); ok {
-		return response, nil
+	model := result.Model
+	if model == "" {
+		model = c.model
 	}
 
-	return "", fmt.Errorf("no response in result")
+	return &GenerateResult{
+		Response:         *result.Response,
+		Model:            model,
+		PromptTokens:     result.PromptEvalCount,
+		CompletionTokens: result.EvalCount,
+	}, nil
 }
 
 // GenerateStream sends a prompt and returns a channel of response chunks
@@ -0,0 +1,333 @@
+package memory
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveSuffix 压缩段的后缀，sidecarSuffix 压缩段摘要的后缀
+const (
+	archiveSuffix = ".gz"
+	sidecarSuffix = ".idx.json"
+	tempSuffix    = ".tmp"
+)
+
+// segmentMeta 压缩段的 sidecar 摘要，查询时不解压即可判断能否命中
+type segmentMeta struct {
+	First    time.Time      `json:"first"`
+	Last     time.Time      `json:"last"`
+	Count    int            `json:"count"`
+	Types    map[string]int `json:"types"`
+	Beacons  []beaconMark   `json:"beacons,omitempty"`
+	RawSize  int64          `json:"raw_size"`           // 压缩前的字节数
+	Unsorted bool           `json:"unsorted,omitempty"` // 段内时间戳不是递增的
+}
+
+// beaconMark 段内的一个信标
+type beaconMark struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// newSegmentMeta 由段索引生成摘要
+func newSegmentMeta(idx *memoryIndex) *segmentMeta {
+	meta := &segmentMeta{
+		First:    idx.first,
+		Last:     idx.last,
+		Types:    make(map[string]int, len(idx.byType)),
+		RawSize:  idx.size,
+		Unsorted: !idx.sorted,
+	}
+	for _, e := range idx.entries {
+		meta.Types[e.typ]++
+		if e.typ == "beacon" {
+			meta.Beacons = append(meta.Beacons, beaconMark{Name: e.taskID, Time: e.ts})
+		}
+	}
+	meta.Count = len(idx.entries)
+	return meta
+}
+
+// mayMatch 根据摘要判断段内是否可能有满足条件的条目
+func (m *segmentMeta) mayMatch(q Query) bool {
+	if m.Count == 0 {
+		return false
+	}
+	if !q.Since.IsZero() && m.Last.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !m.First.Before(q.Until) {
+		return false
+	}
+	if len(q.Types) > 0 {
+		found := false
+		for _, t := range q.Types {
+			if m.Types[t] > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	// 信标按名称查找时可以精确判断
+	if q.TaskID != "" && len(q.Types) == 1 && q.Types[0] == "beacon" {
+		for _, b := range m.Beacons {
+			if b.Name == q.TaskID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// queryArchive 解压整个段后查询，解压结果不缓存
+func queryArchive(path string, q Query) ([]MemoryEntry, error) {
+	data, err := readArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := indexReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return queryIndex(bytes.NewReader(data), idx, q)
+}
+
+// readArchive 读取并解压压缩段
+func readArchive(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory archive: %w", err)
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory archive: %w", err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory archive: %w", err)
+	}
+	return data, nil
+}
+
+// loadArchive 读取压缩段及其摘要，摘要缺失或损坏时解压重建
+func loadArchive(path string) (*segment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := readSidecar(path + sidecarSuffix)
+	if err != nil {
+		data, err := readArchive(path)
+		if err != nil {
+			return nil, err
+		}
+		idx, err := indexReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		meta = newSegmentMeta(idx)
+		if err := writeSidecar(path+sidecarSuffix, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &segment{path: path, size: info.Size(), last: meta.Last, meta: meta}
+	if sidecar, err := os.Stat(path + sidecarSuffix); err == nil {
+		s.size += sidecar.Size()
+	}
+	if s.last.IsZero() {
+		s.last = info.ModTime()
+	}
+	return s, nil
+}
+
+func readSidecar(path string) (*segmentMeta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var meta segmentMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	if meta.Types == nil {
+		meta.Types = make(map[string]int)
+	}
+	return &meta, nil
+}
+
+// writeSidecar 原子写入摘要
+func writeSidecar(path string, meta *segmentMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode memory archive index: %w", err)
+	}
+	tmpPath := path + tempSuffix
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write memory archive index: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write memory archive index: %w", err)
+	}
+	return nil
+}
+
+// removeArchive 删除压缩段及其摘要
+func removeArchive(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(path + sidecarSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeTempArchives 清理中断的压缩留下的临时文件
+func removeTempArchives(filePath string) {
+	matches, _ := filepath.Glob(filePath + ".*.bak" + archiveSuffix + "*" + tempSuffix)
+	for _, path := range matches {
+		os.Remove(path)
+	}
+}
+
+// compressSegment 将原始段压缩为 .bak.gz 并写入摘要，返回压缩段（原始段由调用方删除）
+//
+// 先写摘要再重命名压缩文件，.bak.gz 出现时摘要一定已经就绪。
+func compressSegment(path string, idx *memoryIndex) (*segment, error) {
+	archivePath := path + archiveSuffix
+	meta := newSegmentMeta(idx)
+	if err := writeSidecar(archivePath+sidecarSuffix, meta); err != nil {
+		return nil, err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory segment: %w", err)
+	}
+	defer src.Close()
+
+	tmpPath := archivePath + tempSuffix
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memory archive: %w", err)
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, archivePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		os.Remove(archivePath + sidecarSuffix)
+		return nil, fmt.Errorf("failed to write memory archive: %w", err)
+	}
+
+	s := &segment{path: archivePath, last: meta.Last, meta: meta}
+	for _, p := range []string{archivePath, archivePath + sidecarSuffix} {
+		if info, err := os.Stat(p); err == nil {
+			s.size += info.Size()
+		}
+	}
+	return s, nil
+}
+
+// SetCompression 设置是否压缩轮转段，开启时在后台压缩尚未压缩的段
+func (b *FileBackend) SetCompression(enabled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.compress = enabled
+	b.startArchiverLocked()
+}
+
+// startArchiverLocked 有待压缩的段时启动后台压缩（调用方需持有 b.mu）
+func (b *FileBackend) startArchiverLocked() {
+	if !b.compress || b.archiving {
+		return
+	}
+	b.archiving = true
+	go b.archiveSegments()
+}
+
+// archiveSegments 依次压缩原始段，直到没有待压缩的段
+//
+// 压缩在锁外进行；完成后若段已被保留策略或 Clear 删除，丢弃压缩结果。
+func (b *FileBackend) archiveSegments() {
+	for {
+		b.mu.Lock()
+		var target *segment
+		if b.compress {
+			for _, s := range b.segments {
+				if s.meta == nil && !s.archiveFailed {
+					target = s
+					break
+				}
+			}
+		}
+		if target == nil {
+			b.archiving = false
+			b.mu.Unlock()
+			return
+		}
+		path := target.path
+		idx, err := target.segmentIndex()
+		b.mu.Unlock()
+
+		var archived *segment
+		if err == nil {
+			archived, err = compressSegment(path, idx)
+		}
+
+		b.mu.Lock()
+		if err != nil {
+			log.Printf("Warning: Failed to compress memory segment %s: %v", filepath.Base(path), err)
+			target.archiveFailed = true
+			b.mu.Unlock()
+			continue
+		}
+		replaced := false
+		for i, s := range b.segments {
+			if s == target {
+				b.segments[i] = archived
+				replaced = true
+				break
+			}
+		}
+		if replaced {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Warning: Failed to remove compressed memory segment %s: %v", filepath.Base(path), err)
+			}
+			b.enforceRetentionLocked()
+		} else {
+			removeArchive(archived.path)
+		}
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,343 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 信标相关的记忆类型：信标以追加的方式记录，删除和重命名同样是一条记忆
+const (
+	TypeBeacon        = "beacon"
+	TypeBeaconDeleted = "beacon_deleted"
+	TypeBeaconRenamed = "beacon_renamed"
+)
+
+// 信标错误
+var (
+	ErrBeaconNotFound     = errors.New("beacon not found")
+	ErrInvalidBeaconName  = errors.New("invalid beacon name")
+	ErrBeaconExists       = errors.New("beacon already exists")
+	ErrInvalidBeaconRange = errors.New("invalid beacon range")
+)
+
+// maxBeaconName 信标名称的最大长度
+const maxBeaconName = 128
+
+// IsBeaconType 判断记忆类型是否属于信标
+func IsBeaconType(entryType string) bool {
+	return entryType == TypeBeacon || entryType == TypeBeaconDeleted || entryType == TypeBeaconRenamed
+}
+
+// BeaconOccurrence 信标的一次设置
+type BeaconOccurrence struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Beacon 一个信标
+//
+// 同名信标可以多次设置，每次都是一个出现；按名称引用时取最后一次，
+// "name@N" 引用第 N 次（从 1 开始，按当前保留的记忆计数）。
+type Beacon struct {
+	Name        string             `json:"name"`
+	Timestamp   time.Time          `json:"timestamp"` // 最后一次设置
+	First       time.Time          `json:"first"`
+	Occurrences int                `json:"occurrences"`
+	Data        json.RawMessage    `json:"data,omitempty"`    // 最后一次设置时的元数据
+	History     []BeaconOccurrence `json:"history,omitempty"` // 全部出现，仅 GetBeacon 返回
+}
+
+// beaconRegistry 信标名称到各次出现的映射（按时间正序）
+type beaconRegistry map[string][]BeaconOccurrence
+
+// apply 按记忆更新登记表
+func (r beaconRegistry) apply(entry MemoryEntry) {
+	switch entry.Type {
+	case TypeBeacon:
+		r[entry.TaskID] = append(r[entry.TaskID], BeaconOccurrence{Timestamp: entry.Timestamp, Data: entry.Data})
+	case TypeBeaconDeleted:
+		delete(r, entry.TaskID)
+	case TypeBeaconRenamed:
+		var rename struct {
+			To string `json:"to"`
+		}
+		if json.Unmarshal(entry.Data, &rename) != nil || rename.To == "" {
+			return
+		}
+		moved := append(r[rename.To], r[entry.TaskID]...)
+		sort.SliceStable(moved, func(i, j int) bool { return moved[i].Timestamp.Before(moved[j].Timestamp) })
+		delete(r, entry.TaskID)
+		r[rename.To] = moved
+	}
+}
+
+// registryLocked 返回信标登记表，必要时从记忆重放建立（调用方需持有 m.mu）
+func (m *JSONLMemory) registryLocked() (beaconRegistry, error) {
+	if m.beacons != nil {
+		return m.beacons, nil
+	}
+	entries, err := m.backend.Query(Query{Types: []string{TypeBeacon, TypeBeaconDeleted, TypeBeaconRenamed}})
+	if err != nil {
+		return nil, err
+	}
+	registry := make(beaconRegistry)
+	for _, entry := range entries {
+		registry.apply(entry)
+	}
+	m.beacons = registry
+	return registry, nil
+}
+
+// ValidateBeaconName 检查信标名称：非空、不超过 128 个字符，不含 "/"、"@" 和首尾空白
+func ValidateBeaconName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidBeaconName)
+	case len(name) > maxBeaconName:
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidBeaconName, maxBeaconName)
+	case strings.TrimSpace(name) != name:
+		return fmt.Errorf("%w: leading or trailing whitespace", ErrInvalidBeaconName)
+	case strings.ContainsAny(name, "/@"):
+		return fmt.Errorf("%w: %q must not contain '/' or '@'", ErrInvalidBeaconName, name)
+	}
+	return nil
+}
+
+// SetBeacon 设置记忆信标，同名信标再次设置时记录为新的出现
+func (m *JSONLMemory) SetBeacon(name string, metadata map[string]interface{}) error {
+	if err := ValidateBeaconName(name); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("Beacon set: %s", name)
+	if metadata != nil {
+		return m.Write(TypeBeacon, name, content, metadata)
+	}
+	return m.Write(TypeBeacon, name, content, nil)
+}
+
+// DeleteBeacon 删除信标的全部出现（记忆本身保留）
+func (m *JSONLMemory) DeleteBeacon(name string) error {
+	return m.writeBeaconChange(TypeBeaconDeleted, name, "", fmt.Sprintf("Beacon deleted: %s", name), nil)
+}
+
+// RenameBeacon 重命名信标，目标名称不能已存在
+func (m *JSONLMemory) RenameBeacon(name, newName string) error {
+	if err := ValidateBeaconName(newName); err != nil {
+		return err
+	}
+	return m.writeBeaconChange(TypeBeaconRenamed, name, newName,
+		fmt.Sprintf("Beacon renamed: %s -> %s", name, newName), map[string]string{"to": newName})
+}
+
+// writeBeaconChange 检查信标状态后写入删除或重命名记录，检查与写入在同一把锁内完成
+func (m *JSONLMemory) writeBeaconChange(entryType, name, newName, content string, data interface{}) error {
+	m.mu.Lock()
+	registry, err := m.registryLocked()
+	switch {
+	case err != nil:
+	case len(registry[name]) == 0:
+		err = fmt.Errorf("%w: %s", ErrBeaconNotFound, name)
+	case newName != "" && (newName == name || len(registry[newName]) > 0):
+		err = fmt.Errorf("%w: %s", ErrBeaconExists, newName)
+	}
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	entry, err := m.writeLocked(entryType, name, content, data)
+	m.mu.Unlock()
+
+	if err == nil {
+		m.publish(entry)
+	}
+	return err
+}
+
+// ListBeacons 列出全部信标，按最后一次设置的时间正序
+func (m *JSONLMemory) ListBeacons() ([]Beacon, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registry, err := m.registryLocked()
+	if err != nil {
+		return nil, err
+	}
+	beacons := make([]Beacon, 0, len(registry))
+	for name, occurrences := range registry {
+		beacons = append(beacons, newBeacon(name, occurrences, false))
+	}
+	sort.Slice(beacons, func(i, j int) bool {
+		if !beacons[i].Timestamp.Equal(beacons[j].Timestamp) {
+			return beacons[i].Timestamp.Before(beacons[j].Timestamp)
+		}
+		return beacons[i].Name < beacons[j].Name
+	})
+	return beacons, nil
+}
+
+// GetBeacon 获取信标及其全部出现
+func (m *JSONLMemory) GetBeacon(name string) (*Beacon, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registry, err := m.registryLocked()
+	if err != nil {
+		return nil, err
+	}
+	occurrences := registry[name]
+	if len(occurrences) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBeaconNotFound, name)
+	}
+	beacon := newBeacon(name, occurrences, true)
+	return &beacon, nil
+}
+
+func newBeacon(name string, occurrences []BeaconOccurrence, history bool) Beacon {
+	last := occurrences[len(occurrences)-1]
+	beacon := Beacon{
+		Name:        name,
+		Timestamp:   last.Timestamp,
+		First:       occurrences[0].Timestamp,
+		Occurrences: len(occurrences),
+		Data:        last.Data,
+	}
+	if history {
+		beacon.History = append([]BeaconOccurrence(nil), occurrences...)
+	}
+	return beacon
+}
+
+// resolveBeacon 解析信标引用："name" 取最后一次出现，"name@N" 取第 N 次
+//
+// 先按完整名称查找，名称中含 "@" 的旧信标（如 "release@v2"）仍可直接引用。
+func (m *JSONLMemory) resolveBeacon(ref string) (BeaconOccurrence, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registry, err := m.registryLocked()
+	if err != nil {
+		return BeaconOccurrence{}, err
+	}
+	if occurrences := registry[ref]; len(occurrences) > 0 {
+		return occurrences[len(occurrences)-1], nil
+	}
+
+	name, n := ref, 0
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		v, err := strconv.Atoi(ref[i+1:])
+		if err != nil || v < 1 {
+			return BeaconOccurrence{}, fmt.Errorf("%w: bad occurrence in %q", ErrInvalidBeaconName, ref)
+		}
+		name, n = ref[:i], v
+	}
+	occurrences := registry[name]
+	if len(occurrences) == 0 {
+		return BeaconOccurrence{}, fmt.Errorf("%w: %s", ErrBeaconNotFound, name)
+	}
+	if n == 0 {
+		return occurrences[len(occurrences)-1], nil
+	}
+	if n > len(occurrences) {
+		return BeaconOccurrence{}, fmt.Errorf("%w: %s has %d occurrences", ErrBeaconNotFound, ref, len(occurrences))
+	}
+	return occurrences[n-1], nil
+}
+
+// BeaconTime 返回信标引用对应的时间："name" 为最后一次设置，"name@N" 为第 N 次
+func (m *JSONLMemory) BeaconTime(ref string) (time.Time, error) {
+	occurrence, err := m.resolveBeacon(ref)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return occurrence.Timestamp, nil
+}
+
+// ValueChange 元数据字段在两个信标之间的变化
+type ValueChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// BeaconDiff 两个信标之间的变化摘要
+type BeaconDiff struct {
+	From            string                 `json:"from"`
+	To              string                 `json:"to,omitempty"` // 为空表示到现在
+	Since           time.Time              `json:"since"`
+	Until           time.Time              `json:"until"`
+	Duration        string                 `json:"duration"`
+	Entries         int                    `json:"entries"`
+	ByType          map[string]int         `json:"by_type"`
+	ByTask          map[string]int         `json:"by_task"`
+	MetadataChanges map[string]ValueChange `json:"metadata_changes,omitempty"`
+}
+
+// DiffBeacons 汇总两个信标之间的记忆，并比较两个信标的元数据；to 为空时到现在
+func (m *JSONLMemory) DiffBeacons(from, to string) (*BeaconDiff, error) {
+	start, err := m.resolveBeacon(from)
+	if err != nil {
+		return nil, err
+	}
+	end := BeaconOccurrence{Timestamp: time.Now()}
+	if to != "" {
+		if end, err = m.resolveBeacon(to); err != nil {
+			return nil, err
+		}
+	}
+	if !start.Timestamp.Before(end.Timestamp) {
+		return nil, fmt.Errorf("%w: %s is not before %s", ErrInvalidBeaconRange, from, to)
+	}
+
+	entries, err := m.backend.Query(Query{Since: start.Timestamp, Until: end.Timestamp})
+	if err != nil {
+		return nil, err
+	}
+	diff := &BeaconDiff{
+		From:     from,
+		To:       to,
+		Since:    start.Timestamp,
+		Until:    end.Timestamp,
+		Duration: end.Timestamp.Sub(start.Timestamp).Round(time.Second).String(),
+		Entries:  len(entries),
+		ByType:   make(map[string]int),
+		ByTask:   make(map[string]int),
+	}
+	for _, entry := range entries {
+		diff.ByType[entry.Type]++
+		if entry.TaskID != "" && !IsBeaconType(entry.Type) {
+			diff.ByTask[entry.TaskID]++
+		}
+	}
+	if to != "" {
+		diff.MetadataChanges = metadataChanges(start.Data, end.Data)
+	}
+	return diff, nil
+}
+
+// metadataChanges 比较两个信标的元数据（JSON 对象），返回取值不同的字段
+func metadataChanges(from, to json.RawMessage) map[string]ValueChange {
+	var a, b map[string]interface{}
+	json.Unmarshal(from, &a)
+	json.Unmarshal(to, &b)
+
+	changes := make(map[string]ValueChange)
+	for k, v := range a {
+		if w, ok := b[k]; !ok || !reflect.DeepEqual(v, w) {
+			changes[k] = ValueChange{From: v, To: b[k]}
+		}
+	}
+	for k, w := range b {
+		if _, ok := a[k]; !ok {
+			changes[k] = ValueChange{To: w}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
@@ -0,0 +1,354 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 整合层级与对应的摘要记忆类型
+const (
+	LevelHour = "hour"
+	LevelDay  = "day"
+
+	TypeSummaryHour = "summary_hour"
+	TypeSummaryDay  = "summary_day"
+)
+
+// 摘要来源
+const (
+	SummarySourceLLM   = "llm"
+	SummarySourceRules = "rules"
+)
+
+// ErrInvalidZoom 未知的整合层级
+var ErrInvalidZoom = errors.New("invalid zoom level")
+
+// DefaultConsolidateTypes 默认参与整合的记忆类型（内容较长、数量随执行次数增长的条目）
+var DefaultConsolidateTypes = []string{"task_executed", "task_completed", "task_failed", "task_alert"}
+
+// ruleSnippetRunes 规则摘要中最近一条内容的截断长度
+const ruleSnippetRunes = 200
+
+// SummaryType 返回层级对应的摘要类型
+func SummaryType(level string) (string, bool) {
+	switch level {
+	case LevelHour:
+		return TypeSummaryHour, true
+	case LevelDay:
+		return TypeSummaryDay, true
+	}
+	return "", false
+}
+
+// IsSummaryType 判断记忆类型是否为整合摘要
+func IsSummaryType(entryType string) bool {
+	return entryType == TypeSummaryHour || entryType == TypeSummaryDay
+}
+
+// Summary 摘要记忆的 Data
+//
+// 摘要的时间戳为写入时间，覆盖的时段以 Start、End 为准；原始条目不会删除，可按 Start、End 和 Types 回查
+// （/api/memory?since=<start>&until=<end>&type=<types>），日摘要由当天的小时摘要归纳。
+type Summary struct {
+	Level   string         `json:"level"`
+	Start   time.Time      `json:"start"`
+	End     time.Time      `json:"end"`
+	Types   []string       `json:"types"`   // 被整合的原始记忆类型
+	Entries int            `json:"entries"` // 覆盖的原始条目数
+	ByType  map[string]int `json:"by_type"`
+	ByTask  map[string]int `json:"by_task,omitempty"`
+	Hours   int            `json:"hours,omitempty"` // 日摘要：归纳的小时摘要数
+	Source  string         `json:"source"`
+	Error   string         `json:"error,omitempty"` // 模型失败时的错误，此时内容按规则生成
+}
+
+// Summarizer 用本地模型归纳一个时段的记忆
+//
+// level 为 LevelHour 时 entries 是原始记忆，为 LevelDay 时是当天的小时摘要。
+type Summarizer func(ctx context.Context, level string, start, end time.Time, entries []MemoryEntry) (string, error)
+
+// ConsolidateOptions 整合参数
+type ConsolidateOptions struct {
+	Before       time.Time // 只整合在此之前结束的时段
+	Types        []string  // 参与整合的类型，默认 DefaultConsolidateTypes
+	MaxSummaries int       // 本次最多生成的摘要数（0 表示不限），其余留到下一次
+	Summarize    Summarizer
+	DayLocation  *time.Location // 日摘要的时区，默认 time.Local
+}
+
+// ConsolidateResult 一次整合的结果
+type ConsolidateResult struct {
+	Hourly            int       `json:"hourly"`
+	Daily             int       `json:"daily"`
+	Entries           int       `json:"entries"` // 新的小时摘要覆盖的原始条目数
+	LLMErrors         int       `json:"llm_errors"`
+	ConsolidatedUntil time.Time `json:"consolidated_until,omitempty"` // 小时摘要覆盖到的时间
+	Pending           bool      `json:"pending"`                      // 达到 MaxSummaries，还有时段未整合
+}
+
+// Consolidate 将 Before 之前的记忆整合为小时摘要，再将完整的一天整合为日摘要
+//
+// 每次从上一个摘要之后继续，没有记忆的时段不生成摘要；模型不可用时按规则生成。
+// 同一时刻只有一个整合在进行。
+func (m *JSONLMemory) Consolidate(ctx context.Context, opts ConsolidateOptions) (*ConsolidateResult, error) {
+	m.consolidateMu.Lock()
+	defer m.consolidateMu.Unlock()
+
+	types := opts.Types
+	if len(types) == 0 {
+		types = DefaultConsolidateTypes
+	}
+	loc := opts.DayLocation
+	if loc == nil {
+		loc = time.Local
+	}
+	result := &ConsolidateResult{}
+	budget := func() bool { return opts.MaxSummaries <= 0 || result.Hourly+result.Daily < opts.MaxSummaries }
+
+	// 小时摘要：从最后一个小时摘要之后、按有记忆的小时逐个推进
+	cutoff := opts.Before.Truncate(time.Hour)
+	cursor, err := m.summarizedUntil(TypeSummaryHour, time.Hour, loc)
+	if err != nil {
+		return nil, err
+	}
+	caughtUp := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		next, err := m.backend.Query(Query{Types: types, Since: cursor, Until: cutoff, Limit: 1})
+		if err != nil {
+			return result, err
+		}
+		if len(next) == 0 {
+			caughtUp = true
+			break
+		}
+		if !budget() {
+			result.Pending = true
+			break
+		}
+		start := next[0].Timestamp.Truncate(time.Hour)
+		end := start.Add(time.Hour)
+		entries, err := m.backend.Query(Query{Types: types, Since: start, Until: end})
+		if err != nil {
+			return result, err
+		}
+		summary := Summary{Level: LevelHour, Start: start, End: end, Types: types, ByType: make(map[string]int), ByTask: make(map[string]int)}
+		for _, entry := range entries {
+			summary.Entries++
+			summary.ByType[entry.Type]++
+			if entry.TaskID != "" {
+				summary.ByTask[entry.TaskID]++
+			}
+		}
+		fallback, err := m.writeSummary(ctx, opts.Summarize, summary, entries)
+		if err != nil {
+			return result, err
+		}
+		if fallback {
+			result.LLMErrors++
+		}
+		result.Hourly++
+		result.Entries += summary.Entries
+		cursor = end
+	}
+	if caughtUp && cutoff.After(cursor) {
+		cursor = cutoff
+	}
+	result.ConsolidatedUntil = cursor
+
+	// 日摘要：小时摘要已经覆盖到当天结束的日期
+	dayCursor, err := m.summarizedUntil(TypeSummaryDay, 0, loc)
+	if err != nil {
+		return result, err
+	}
+	// 摘要在时段结束后写入，时间戳不早于起点，可以作为查询下界
+	written, err := m.backend.Query(Query{Types: []string{TypeSummaryHour}, Since: dayCursor})
+	if err != nil {
+		return result, err
+	}
+	byDay := make(map[time.Time][]MemoryEntry)
+	var days []time.Time
+	for _, entry := range written {
+		hourStart := SummaryStart(entry)
+		if hourStart.Before(dayCursor) || !hourStart.Before(cursor) {
+			continue
+		}
+		day := startOfDay(hourStart, loc)
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], entry)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	for _, start := range days {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		end := start.AddDate(0, 0, 1)
+		if end.After(cursor) {
+			break
+		}
+		if !budget() {
+			result.Pending = true
+			break
+		}
+		hours := byDay[start]
+		sort.SliceStable(hours, func(i, j int) bool { return SummaryStart(hours[i]).Before(SummaryStart(hours[j])) })
+		summary := Summary{Level: LevelDay, Start: start, End: end, Types: types, Hours: len(hours), ByType: make(map[string]int), ByTask: make(map[string]int)}
+		for _, entry := range hours {
+			var hour Summary
+			if json.Unmarshal(entry.Data, &hour) != nil {
+				continue
+			}
+			summary.Entries += hour.Entries
+			for k, v := range hour.ByType {
+				summary.ByType[k] += v
+			}
+			for k, v := range hour.ByTask {
+				summary.ByTask[k] += v
+			}
+		}
+		fallback, err := m.writeSummary(ctx, opts.Summarize, summary, hours)
+		if err != nil {
+			return result, err
+		}
+		if fallback {
+			result.LLMErrors++
+		}
+		result.Daily++
+	}
+	return result, nil
+}
+
+// SummaryStart 返回摘要覆盖时段的起点；旧版本写入的摘要没有 Start 时使用时间戳
+func SummaryStart(entry MemoryEntry) time.Time {
+	var summary Summary
+	if json.Unmarshal(entry.Data, &summary) == nil && !summary.Start.IsZero() {
+		return summary.Start
+	}
+	return entry.Timestamp
+}
+
+// summarizedUntil 返回最后一个该类型摘要的结束时间，没有摘要时返回零值
+func (m *JSONLMemory) summarizedUntil(summaryType string, period time.Duration, loc *time.Location) (time.Time, error) {
+	last, err := m.backend.Query(Query{Types: []string{summaryType}, Limit: 1, Newest: true})
+	if err != nil || len(last) == 0 {
+		return time.Time{}, err
+	}
+	var summary Summary
+	if json.Unmarshal(last[0].Data, &summary) == nil && !summary.End.IsZero() {
+		return summary.End, nil
+	}
+	if period > 0 {
+		return last[0].Timestamp.Add(period), nil
+	}
+	return startOfDay(last[0].Timestamp, loc).AddDate(0, 0, 1), nil
+}
+
+// ConsolidatedUntil 返回该层级的摘要覆盖到的时间，没有摘要时返回零值
+func (m *JSONLMemory) ConsolidatedUntil(level string) (time.Time, error) {
+	summaryType, ok := SummaryType(level)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidZoom, level)
+	}
+	period := time.Duration(0)
+	if level == LevelHour {
+		period = time.Hour
+	}
+	return m.summarizedUntil(summaryType, period, time.Local)
+}
+
+// writeSummary 生成摘要内容并以当前时间为时间戳写入，模型失败而改用规则摘要时返回 true
+//
+// 时间戳不回填到时段起点，否则文件后端的当前文件不再按时间有序，之后的查询都要全量扫描。
+func (m *JSONLMemory) writeSummary(ctx context.Context, summarize Summarizer, summary Summary, entries []MemoryEntry) (bool, error) {
+	summary.Source = SummarySourceRules
+	content := ""
+	if summarize != nil {
+		text, err := summarize(ctx, summary.Level, summary.Start, summary.End, entries)
+		if err == nil && strings.TrimSpace(text) == "" {
+			err = fmt.Errorf("model returned an empty summary")
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			summary.Error = err.Error()
+		} else {
+			content = strings.TrimSpace(text)
+			summary.Source = SummarySourceLLM
+		}
+	}
+	if content == "" {
+		content = ruleSummary(summary, entries)
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return false, err
+	}
+	entryType, _ := SummaryType(summary.Level)
+	entry := MemoryEntry{Timestamp: time.Now(), Type: entryType, Content: content, Data: data}
+
+	m.mu.Lock()
+	err = m.backend.Append(entry)
+	m.mu.Unlock()
+	if err != nil {
+		return false, fmt.Errorf("failed to write %s summary: %w", summary.Level, err)
+	}
+	m.publish(entry)
+	return summary.Error != "", nil
+}
+
+// ruleSummary 模型不可用时的摘要：按类型和任务计数，附最近一条内容
+func ruleSummary(summary Summary, entries []MemoryEntry) string {
+	var b strings.Builder
+	if summary.Level == LevelDay {
+		fmt.Fprintf(&b, "%d hourly summaries, ", summary.Hours)
+	}
+	fmt.Fprintf(&b, "%d entries (%s)", summary.Entries, formatCounts(summary.ByType))
+	if len(summary.ByTask) > 0 {
+		fmt.Fprintf(&b, "; tasks: %s", formatCounts(summary.ByTask))
+	}
+	if len(entries) > 0 {
+		last := strings.Join(strings.Fields(entries[len(entries)-1].Content), " ")
+		if runes := []rune(last); len(runes) > ruleSnippetRunes {
+			last = string(runes[:ruleSnippetRunes]) + "…"
+		}
+		fmt.Fprintf(&b, "; last: %s", last)
+	}
+	return b.String()
+}
+
+// formatCounts 按数量从多到少输出 "a 3, b 1"
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// startOfDay 返回 t 所在日期在 loc 时区的零点
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,360 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// 导出格式
+const (
+	FormatJSONL   = "jsonl"
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+// ErrUnsupportedFormat 不支持的导出或导入格式
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// maxImportLine 导入时单行的最大字节数
+const maxImportLine = 16 * 1024 * 1024
+
+// ValidExportFormat 判断导出格式是否受支持
+func ValidExportFormat(format string) bool {
+	return format == FormatJSONL || format == FormatCSV || format == FormatParquet
+}
+
+// resolveWindow 将信标解析为时间范围，之后的分页不再受信标重新设置的影响
+func (m *JSONLMemory) resolveWindow(s *Search) error {
+	if s.StartBeacon != "" {
+		t, err := m.BeaconTime(s.StartBeacon)
+		if err != nil {
+			return err
+		}
+		if t.After(s.Since) {
+			s.Since = t
+		}
+		s.StartBeacon = ""
+	}
+	if s.EndBeacon != "" {
+		t, err := m.BeaconTime(s.EndBeacon)
+		if err != nil {
+			return err
+		}
+		if s.Until.IsZero() || t.Before(s.Until) {
+			s.Until = t
+		}
+		s.EndBeacon = ""
+	}
+	return nil
+}
+
+// Each 按时间正序逐页读取满足条件的记忆（忽略 Limit、Desc 和 Cursor）
+func (m *JSONLMemory) Each(s Search, fn func(MemoryEntry) error) error {
+	if err := m.resolveWindow(&s); err != nil {
+		return err
+	}
+	s.Limit = MaxSearchLimit
+	s.Desc = false
+	s.Cursor = ""
+	for {
+		page, err := m.Search(s)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page.Entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		s.Cursor = page.NextCursor
+	}
+}
+
+// Export 将满足条件的记忆以指定格式写入 w，返回导出的条数
+//
+// 信标在写入之前解析，信标不存在等错误发生时 w 中没有任何输出。
+func (m *JSONLMemory) Export(w io.Writer, format string, s Search) (int, error) {
+	if !ValidExportFormat(format) {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if err := m.resolveWindow(&s); err != nil {
+		return 0, err
+	}
+
+	switch format {
+	case FormatCSV:
+		return m.exportCSV(w, s)
+	case FormatParquet:
+		return m.exportParquet(w, s)
+	default:
+		return m.exportJSONL(w, s)
+	}
+}
+
+func (m *JSONLMemory) exportJSONL(w io.Writer, s Search) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	count := 0
+	err := m.Each(s, func(entry MemoryEntry) error {
+		count++
+		return enc.Encode(entry)
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, bw.Flush()
+}
+
+// exportCSV 导出 CSV，Data 中的字段展开为 data.<路径> 列
+//
+// 第一遍收集全部列名，第二遍写入，不需要把记忆全部放进内存。
+func (m *JSONLMemory) exportCSV(w io.Writer, s Search) (int, error) {
+	keys := make(map[string]bool)
+	err := m.Each(s, func(entry MemoryEntry) error {
+		for key := range flattenData(entry.Data) {
+			keys[key] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	columns := make([]string, 0, len(keys))
+	for key := range keys {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+
+	cw := csv.NewWriter(w)
+	header := append([]string{"timestamp", "type", "task_id", "content"}, columns...)
+	if err := cw.Write(header); err != nil {
+		return 0, err
+	}
+	count := 0
+	err = m.Each(s, func(entry MemoryEntry) error {
+		fields := flattenData(entry.Data)
+		record := []string{entry.Timestamp.Format(time.RFC3339Nano), entry.Type, entry.TaskID, entry.Content}
+		for _, column := range columns {
+			record = append(record, fields[column])
+		}
+		count++
+		return cw.Write(record)
+	})
+	if err != nil {
+		return count, err
+	}
+	cw.Flush()
+	return count, cw.Error()
+}
+
+// flattenData 将 Data 展开为 "data.a.b" → 值；数组保留为 JSON，非对象的 Data 放在 "data" 列
+func flattenData(data json.RawMessage) map[string]string {
+	fields := make(map[string]string)
+	if len(data) == 0 {
+		return fields
+	}
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if dec.Decode(&value) != nil {
+		fields["data"] = string(data)
+		return fields
+	}
+	flattenValue("data", value, fields)
+	return fields
+}
+
+func flattenValue(prefix string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			flattenValue(prefix+"."+key, child, fields)
+		}
+	case nil:
+		fields[prefix] = ""
+	case string:
+		fields[prefix] = v
+	case json.Number:
+		fields[prefix] = v.String()
+	case bool:
+		fields[prefix] = strconv.FormatBool(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		fields[prefix] = string(encoded)
+	}
+}
+
+// parquetEntry Parquet 导出的行结构，Data 保留为 JSON 列
+type parquetEntry struct {
+	Timestamp time.Time `parquet:"timestamp,timestamp(nanosecond)"`
+	Type      string    `parquet:"type,dict"`
+	TaskID    string    `parquet:"task_id,optional,dict"`
+	Content   string    `parquet:"content"`
+	Data      string    `parquet:"data,optional,json"`
+}
+
+// parquetBatch 每批写入的行数
+const parquetBatch = 1000
+
+func (m *JSONLMemory) exportParquet(w io.Writer, s Search) (int, error) {
+	pw := parquet.NewGenericWriter[parquetEntry](w,
+		parquet.Compression(&parquet.Snappy),
+		parquet.MaxRowsPerRowGroup(64*1024))
+
+	count := 0
+	batch := make([]parquetEntry, 0, parquetBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := pw.Write(batch)
+		batch = batch[:0]
+		return err
+	}
+	err := m.Each(s, func(entry MemoryEntry) error {
+		batch = append(batch, parquetEntry{
+			Timestamp: entry.Timestamp,
+			Type:      entry.Type,
+			TaskID:    entry.TaskID,
+			Content:   entry.Content,
+			Data:      string(entry.Data),
+		})
+		count++
+		if len(batch) == parquetBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return count, err
+	}
+	return count, pw.Close()
+}
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Total      int `json:"total"`      // 读取的条目数
+	Imported   int `json:"imported"`   // 新写入的条目数
+	Duplicates int `json:"duplicates"` // 已存在（或在导入数据中重复）而跳过的条目数
+	Invalid    int `json:"invalid"`    // 无法解析或缺少时间、类型而跳过的行数
+}
+
+// batchImporter 支持批量写入的后端（如 SQLite 在一个事务中写入）
+type batchImporter interface {
+	Import(entries []MemoryEntry) error
+}
+
+// Import 从 JSONL（Export 的输出或另一个实例的记忆文件）导入记忆
+//
+// 时间、类型、任务、内容和数据完全相同的条目视为重复，只保留一份；
+// 导入的条目按时间排序后写入，不触发监听函数。
+func (m *JSONLMemory) Import(r io.Reader) (*ImportResult, error) {
+	result := &ImportResult{}
+
+	var entries []MemoryEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry MemoryEntry
+		if json.Unmarshal(line, &entry) != nil || entry.Timestamp.IsZero() || entry.Type == "" {
+			result.Invalid++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read import: %w", err)
+	}
+	result.Total = len(entries)
+	if len(entries) == 0 {
+		return result, nil
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 只需要比较导入数据时间范围内已有的条目
+	first, last := entries[0].Timestamp, entries[len(entries)-1].Timestamp
+	existing, err := m.backend.Query(Query{Since: first, Until: last.Add(time.Nanosecond)})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[[sha256.Size]byte]bool, len(existing)+len(entries))
+	for _, entry := range existing {
+		seen[entryKey(entry)] = true
+	}
+
+	fresh := entries[:0]
+	for _, entry := range entries {
+		key := entryKey(entry)
+		if seen[key] {
+			result.Duplicates++
+			continue
+		}
+		seen[key] = true
+		fresh = append(fresh, entry)
+	}
+	if len(fresh) == 0 {
+		return result, nil
+	}
+
+	if importer, ok := m.backend.(batchImporter); ok {
+		err = importer.Import(fresh)
+		if err == nil {
+			result.Imported = len(fresh)
+		}
+	} else {
+		for _, entry := range fresh {
+			if err = m.backend.Append(entry); err != nil {
+				break
+			}
+			result.Imported++
+		}
+	}
+	// 导入的数据中可能有信标，重新建立登记表
+	m.beacons = nil
+	if err != nil {
+		return result, fmt.Errorf("failed to import memory: %w", err)
+	}
+	return result, nil
+}
+
+// entryKey 条目的去重键
+func entryKey(entry MemoryEntry) [sha256.Size]byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s\x00", entry.Timestamp.UnixNano(), entry.Type, entry.TaskID, entry.Content)
+	if len(entry.Data) > 0 {
+		var compact bytes.Buffer
+		if json.Compact(&compact, entry.Data) == nil {
+			h.Write(compact.Bytes())
+		} else {
+			h.Write(entry.Data)
+		}
+	}
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileBackend 将记忆以 JSONL 格式追加到文件，超过 maxSize 时轮转为只读段
+//
+// 启动时为当前文件建立内存索引并在写入时更新，查询只读取命中的行；
+// 轮转段与当前文件一起作为一个日志查询，按 maxTotal/maxAge 删除最旧的段；
+// 开启压缩时轮转段在后台压缩为 gzip 并附带摘要索引。
+type FileBackend struct {
+	filePath  string
+	mu        sync.Mutex
+	maxSize   int64
+	maxTotal  int64
+	maxAge    time.Duration
+	index     *memoryIndex
+	segments  []*segment // 轮转段，从旧到新
+	compress  bool
+	archiving bool // 后台压缩正在运行
+}
+
+// NewFileBackend 创建 JSONL 文件后端
+func NewFileBackend(dataDir string) (*FileBackend, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	b := &FileBackend{
+		filePath: filepath.Join(dataDir, "cerebellum_memory.jsonl"),
+		maxSize:  10 * 1024 * 1024,
+		maxTotal: defaultMaxTotal,
+	}
+
+	if _, err := os.Stat(b.filePath); os.IsNotExist(err) {
+		file, err := os.Create(b.filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create memory file: %w", err)
+		}
+		file.Close()
+	}
+
+	index, err := buildIndex(b.filePath)
+	if err != nil {
+		return nil, err
+	}
+	b.index = index
+
+	if b.segments, err = loadSegments(b.filePath); err != nil {
+		return nil, fmt.Errorf("failed to list memory segments: %w", err)
+	}
+	b.enforceRetentionLocked()
+
+	return b, nil
+}
+
+// ensureIndexLocked 文件大小与索引不一致（被外部修改或上次写入失败）时重建索引（调用方需持有 b.mu）
+func (b *FileBackend) ensureIndexLocked() error {
+	info, err := os.Stat(b.filePath)
+	if os.IsNotExist(err) {
+		if b.index.size != 0 {
+			b.index = newMemoryIndex()
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat memory file: %w", err)
+	}
+	if info.Size() == b.index.size {
+		return nil
+	}
+
+	index, err := buildIndex(b.filePath)
+	if err != nil {
+		return err
+	}
+	b.index = index
+	return nil
+}
+
+// Append 追加一条记忆
+func (b *FileBackend) Append(entry MemoryEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.rotateIfNeeded(); err != nil {
+		return err
+	}
+	if err := b.ensureIndexLocked(); err != nil {
+		return err
+	}
+	b.enforceRetentionLocked()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	file, err := os.OpenFile(b.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open memory file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+
+	b.index.add(indexEntry{
+		offset: b.index.size,
+		length: len(line),
+		ts:     entry.Timestamp,
+		typ:    entry.Type,
+		taskID: entry.TaskID,
+	})
+	b.index.size += int64(len(line))
+	return nil
+}
+
+// Query 按条件查询记忆（包括轮转段），通过索引定位后只读取命中的条目
+func (b *FileBackend) Query(q Query) ([]MemoryEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.ensureIndexLocked(); err != nil {
+		return nil, err
+	}
+	return b.queryLocked(q)
+}
+
+// Stats 按类型统计记忆条数
+func (b *FileBackend) Stats() (map[string]int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.ensureIndexLocked(); err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]int)
+	for _, s := range b.allSegments() {
+		counts, err := s.stats()
+		if err != nil {
+			return nil, err
+		}
+		for typ, n := range counts {
+			stats[typ] += n
+		}
+	}
+
+	return stats, nil
+}
+
+// Clear 删除记忆文件及全部轮转段
+func (b *FileBackend) Clear() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.clearSegmentsLocked(); err != nil {
+		return err
+	}
+	b.index = newMemoryIndex()
+	return os.Remove(b.filePath)
+}
+
+// SetMaxSize 设置最大文件大小
+func (b *FileBackend) SetMaxSize(size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maxSize = size
+}
+
+// rotateIfNeeded 如果需要则轮转文件（调用方需持有 b.mu）
+func (b *FileBackend) rotateIfNeeded() error {
+	info, err := os.Stat(b.filePath)
+	if err != nil {
+		return nil
+	}
+
+	if info.Size() < b.maxSize {
+		return nil
+	}
+
+	timestamp := time.Now().Format(rotateLayout)
+	backupPath := b.filePath + "." + timestamp + ".bak"
+	// 同一秒内多次轮转时加序号，避免覆盖已有的段（包括已压缩的段）
+	for i := 1; fileExists(backupPath) || fileExists(backupPath+archiveSuffix); i++ {
+		backupPath = fmt.Sprintf("%s.%s_%d.bak", b.filePath, timestamp, i)
+	}
+
+	if err := os.Rename(b.filePath, backupPath); err != nil {
+		return fmt.Errorf("failed to rotate memory file: %w", err)
+	}
+	b.sealLocked(backupPath, info)
+
+	return nil
+}
+
+// rotateLayout 轮转段文件名中的时间格式
+const rotateLayout = "20060102_150405"
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Matches 判断条目是否满足查询条件（不考虑 Limit）
+func (q Query) Matches(entry MemoryEntry) bool {
+	if len(q.Types) > 0 {
+		found := false
+		for _, t := range q.Types {
+			if entry.Type == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.TaskID != "" && entry.TaskID != q.TaskID {
+		return false
+	}
+	if !q.Since.IsZero() && entry.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !entry.Timestamp.Before(q.Until) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,287 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// indexEntry 一条记忆在文件中的位置和用于过滤的字段
+type indexEntry struct {
+	offset int64
+	length int
+	ts     time.Time
+	typ    string
+	taskID string
+}
+
+// memoryIndex 记忆文件的内存索引
+//
+// entries 按文件顺序排列；byType/byTask 保存 entries 中的下标，同样递增。
+// 时间戳通常随写入递增，可以二分查找；出现回退（如系统时钟调整或导入旧记忆）时
+// sorted 为 false，时间范围退回逐条比较，结果排序后再截取。
+type memoryIndex struct {
+	entries     []indexEntry
+	byType      map[string][]int
+	byTask      map[string][]int
+	size        int64 // 已索引的文件字节数
+	sorted      bool
+	first, last time.Time // 最早和最晚的时间戳
+}
+
+func newMemoryIndex() *memoryIndex {
+	return &memoryIndex{
+		byType: make(map[string][]int),
+		byTask: make(map[string][]int),
+		sorted: true,
+	}
+}
+
+// add 索引一条记忆
+func (idx *memoryIndex) add(e indexEntry) {
+	if n := len(idx.entries); n > 0 && e.ts.Before(idx.entries[n-1].ts) {
+		idx.sorted = false
+	}
+	if len(idx.entries) == 0 || e.ts.Before(idx.first) {
+		idx.first = e.ts
+	}
+	if len(idx.entries) == 0 || e.ts.After(idx.last) {
+		idx.last = e.ts
+	}
+	pos := len(idx.entries)
+	idx.entries = append(idx.entries, e)
+	idx.byType[e.typ] = append(idx.byType[e.typ], pos)
+	if e.taskID != "" {
+		idx.byTask[e.taskID] = append(idx.byTask[e.taskID], pos)
+	}
+}
+
+// buildIndex 扫描文件建立索引，文件不存在时返回空索引
+func buildIndex(path string) (*memoryIndex, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return newMemoryIndex(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory file: %w", err)
+	}
+	defer file.Close()
+
+	return indexReader(file)
+}
+
+// indexReader 逐行扫描 JSONL 建立索引，无法解析的行被跳过（与逐行读取时一致）
+func indexReader(r io.Reader) (*memoryIndex, error) {
+	idx := newMemoryIndex()
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var head struct {
+				Timestamp time.Time `json:"timestamp"`
+				Type      string    `json:"type"`
+				TaskID    string    `json:"task_id"`
+			}
+			if json.Unmarshal(line, &head) == nil {
+				idx.add(indexEntry{offset: offset, length: len(line), ts: head.Timestamp, typ: head.Type, taskID: head.TaskID})
+			}
+			offset += int64(len(line))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read memory file: %w", err)
+		}
+	}
+	idx.size = offset
+	return idx, nil
+}
+
+// candidates 返回可能满足条件的下标（递增），选择最小的候选集合
+func (idx *memoryIndex) candidates(q Query) []int {
+	if q.TaskID != "" {
+		list := idx.byTask[q.TaskID]
+		if len(q.Types) == 1 && len(idx.byType[q.Types[0]]) < len(list) {
+			return idx.byType[q.Types[0]]
+		}
+		return list
+	}
+	switch len(q.Types) {
+	case 0:
+		return nil // 全部条目，由调用方按范围遍历
+	case 1:
+		return idx.byType[q.Types[0]]
+	}
+
+	var merged []int
+	seen := make(map[string]bool, len(q.Types))
+	for _, t := range q.Types {
+		if !seen[t] {
+			seen[t] = true
+			merged = append(merged, idx.byType[t]...)
+		}
+	}
+	sort.Ints(merged)
+	return merged
+}
+
+// search 返回满足条件的下标（按文件顺序），Newest 时从末尾反向查找
+func (idx *memoryIndex) search(q Query) []int {
+	all := len(q.Types) == 0 && q.TaskID == ""
+	list := idx.candidates(q)
+	n := len(list)
+	if all {
+		n = len(idx.entries)
+	}
+	at := func(i int) int {
+		if all {
+			return i
+		}
+		return list[i]
+	}
+
+	// 时间有序时用二分查找缩小范围
+	lo, hi := 0, n
+	if idx.sorted {
+		if !q.Since.IsZero() {
+			lo = sort.Search(n, func(i int) bool { return !idx.entries[at(i)].ts.Before(q.Since) })
+		}
+		if !q.Until.IsZero() {
+			hi = sort.Search(n, func(i int) bool { return !idx.entries[at(i)].ts.Before(q.Until) })
+		}
+	}
+
+	if !idx.sorted {
+		return idx.sortedMatches(q, n, at)
+	}
+
+	var result []int
+	match := func(i int) bool {
+		pos := at(i)
+		if q.matchesIndex(idx.entries[pos]) {
+			result = append(result, pos)
+		}
+		return q.Limit > 0 && len(result) >= q.Limit
+	}
+	if q.Newest {
+		for i := hi - 1; i >= lo; i-- {
+			if match(i) {
+				break
+			}
+		}
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+		return result
+	}
+	for i := lo; i < hi; i++ {
+		if match(i) {
+			break
+		}
+	}
+	return result
+}
+
+// sortedMatches 时间无序时取出全部匹配的下标，按时间排序（同一时间保持文件顺序）后再截取
+func (idx *memoryIndex) sortedMatches(q Query, n int, at func(int) int) []int {
+	var result []int
+	for i := 0; i < n; i++ {
+		if pos := at(i); q.matchesIndex(idx.entries[pos]) {
+			result = append(result, pos)
+		}
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		return idx.entries[result[a]].ts.Before(idx.entries[result[b]].ts)
+	})
+	if q.Limit > 0 && len(result) > q.Limit {
+		if q.Newest {
+			return result[len(result)-q.Limit:]
+		}
+		return result[:q.Limit]
+	}
+	return result
+}
+
+// matchesIndex 用索引字段判断条目是否满足查询条件
+func (q Query) matchesIndex(e indexEntry) bool {
+	return q.Matches(MemoryEntry{Timestamp: e.ts, Type: e.typ, TaskID: e.taskID})
+}
+
+// queryFile 通过索引查询文件中的条目
+func queryFile(path string, idx *memoryIndex, q Query) ([]MemoryEntry, error) {
+	if len(idx.entries) == 0 {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory file: %w", err)
+	}
+	defer file.Close()
+
+	return queryIndex(file, idx, q)
+}
+
+// queryIndex 通过索引查询条目；有 Match 时需要读取全部候选条目后再过滤
+func queryIndex(r io.ReaderAt, idx *memoryIndex, q Query) ([]MemoryEntry, error) {
+	if q.Match == nil {
+		return readEntries(r, idx, idx.search(q))
+	}
+	all := q
+	all.Limit = 0
+	entries, err := readEntries(r, idx, idx.search(all))
+	if err != nil {
+		return nil, err
+	}
+	return q.FilterMatch(entries), nil
+}
+
+// FilterMatch 用 Match 过滤按时间正序排列的条目，再按查询方向保留 Limit 条
+func (q Query) FilterMatch(entries []MemoryEntry) []MemoryEntry {
+	if q.Match == nil {
+		return entries
+	}
+	filtered := entries[:0]
+	for _, e := range entries {
+		if q.Match(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	if q.Limit > 0 && len(filtered) > q.Limit {
+		if q.Newest {
+			return filtered[len(filtered)-q.Limit:]
+		}
+		return filtered[:q.Limit]
+	}
+	return filtered
+}
+
+// readEntries 按索引位置读取条目
+func readEntries(r io.ReaderAt, idx *memoryIndex, positions []int) ([]MemoryEntry, error) {
+	entries := make([]MemoryEntry, 0, len(positions))
+	var buf []byte
+	for _, pos := range positions {
+		e := idx.entries[pos]
+		if cap(buf) < e.length {
+			buf = make([]byte, e.length)
+		}
+		buf = buf[:e.length]
+		if _, err := r.ReadAt(buf, e.offset); err != nil {
+			return nil, fmt.Errorf("failed to read memory entry: %w", err)
+		}
+
+		var entry MemoryEntry
+		if err := json.Unmarshal(buf, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -1,11 +1,9 @@
 package memory
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 )
@@ -19,44 +17,75 @@ type MemoryEntry struct {
 	Data      json.RawMessage `json:"data,omitempty"`
 }
 
-// JSONLMemory JSONL 记忆管理器
+// Query 记忆查询条件，零值字段不过滤
+type Query struct {
+	Types  []string
+	TaskID string
+	Since  time.Time // 包含
+	Until  time.Time // 不包含
+	Limit  int
+	Newest bool // 取最新的 Limit 条（结果仍按时间正序）
+
+	// Match 读取条目后的附加过滤（如内容匹配），不参与索引；设置后后端先过滤再截取 Limit 条
+	Match func(MemoryEntry) bool
+}
+
+// Backend 记忆存储后端
+type Backend interface {
+	Append(entry MemoryEntry) error
+	Query(q Query) ([]MemoryEntry, error)
+	Stats() (map[string]int, error)
+	Clear() error
+}
+
+// JSONLMemory 记忆管理器（默认使用 JSONL 文件后端）
 type JSONLMemory struct {
-	filePath string
-	mu       sync.Mutex
-	maxSize  int64
+	backend Backend
+	mu      sync.Mutex
+	beacons beaconRegistry // 信标登记表，首次使用时从记忆重放建立
+
+	subMu       sync.RWMutex // 保护 listener 和 subscribers
+	listener    func(MemoryEntry)
+	subscribers map[*Subscription]struct{}
+
+	consolidateMu sync.Mutex // 保证同一时刻只有一个整合在进行
 }
 
 // NewJSONLMemory 创建新的 JSONL 记忆管理器
 func NewJSONLMemory(dataDir string) (*JSONLMemory, error) {
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
-
-	m := &JSONLMemory{
-		filePath: filepath.Join(dataDir, "cerebellum_memory.jsonl"),
-		maxSize:  10 * 1024 * 1024,
-	}
-
-	if _, err := os.Stat(m.filePath); os.IsNotExist(err) {
-		file, err := os.Create(m.filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create memory file: %w", err)
-		}
-		file.Close()
+	backend, err := NewFileBackend(dataDir)
+	if err != nil {
+		return nil, err
 	}
+	return NewWithBackend(backend), nil
+}
 
-	return m, nil
+// NewWithBackend 使用指定的存储后端创建记忆管理器
+func NewWithBackend(backend Backend) *JSONLMemory {
+	return &JSONLMemory{backend: backend}
 }
 
 // Write 写入记忆
 func (m *JSONLMemory) Write(entryType string, taskID string, content string, data interface{}) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	entry, err := m.writeLocked(entryType, taskID, content, data)
+	m.mu.Unlock()
 
-	if err := m.rotateIfNeeded(); err != nil {
-		return err
+	if err == nil {
+		m.publish(entry)
 	}
+	return err
+}
 
+// SetListener 设置写入成功后的监听函数（同步调用，不能阻塞）
+func (m *JSONLMemory) SetListener(fn func(MemoryEntry)) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	m.listener = fn
+}
+
+// writeLocked 构造条目并写入后端（调用方需持有 m.mu）
+func (m *JSONLMemory) writeLocked(entryType string, taskID string, content string, data interface{}) (MemoryEntry, error) {
 	entry := MemoryEntry{
 		Timestamp: time.Now(),
 		Type:      entryType,
@@ -67,98 +96,38 @@ func (m *JSONLMemory) Write(entryType string, taskID string, content string, dat
 	if data != nil {
 		dataBytes, err := json.Marshal(data)
 		if err != nil {
-			return fmt.Errorf("failed to marshal data: %w", err)
+			return entry, fmt.Errorf("failed to marshal data: %w", err)
 		}
 		entry.Data = dataBytes
 	}
 
-	file, err := os.OpenFile(m.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open memory file: %w", err)
+	if err := m.backend.Append(entry); err != nil {
+		return entry, err
 	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	if err := encoder.Encode(entry); err != nil {
-		return fmt.Errorf("failed to encode entry: %w", err)
+	if m.beacons != nil {
+		m.beacons.apply(entry)
 	}
-
-	return nil
+	return entry, nil
 }
 
 // ReadAll 读取所有记忆
 func (m *JSONLMemory) ReadAll() ([]MemoryEntry, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	file, err := os.Open(m.filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open memory file: %w", err)
-	}
-	defer file.Close()
-
-	var entries []MemoryEntry
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			continue
-		}
-
-		var entry MemoryEntry
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
-		}
-		entries = append(entries, entry)
-	}
-
-	return entries, scanner.Err()
+	return m.backend.Query(Query{})
 }
 
 // ReadRecent 读取最近的 n 条记忆
 func (m *JSONLMemory) ReadRecent(n int) ([]MemoryEntry, error) {
-	entries, err := m.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	if len(entries) <= n {
-		return entries, nil
-	}
-
-	return entries[len(entries)-n:], nil
+	return m.backend.Query(Query{Limit: n, Newest: true})
 }
 
 // ReadByType 读取特定类型的记忆
 func (m *JSONLMemory) ReadByType(entryType string, limit int) ([]MemoryEntry, error) {
-	entries, err := m.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	var filtered []MemoryEntry
-	for i := len(entries) - 1; i >= 0 && len(filtered) < limit; i-- {
-		if entries[i].Type == entryType {
-			filtered = append([]MemoryEntry{entries[i]}, filtered...)
-		}
-	}
-
-	return filtered, nil
+	return m.backend.Query(Query{Types: []string{entryType}, Limit: limit, Newest: true})
 }
 
 // GetStats 获取记忆统计
 func (m *JSONLMemory) GetStats() (map[string]int, error) {
-	entries, err := m.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	stats := make(map[string]int)
-	for _, entry := range entries {
-		stats[entry.Type]++
-	}
-
-	return stats, nil
+	return m.backend.Stats()
 }
 
 // Clear 清空记忆
@@ -166,123 +135,65 @@ func (m *JSONLMemory) Clear() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	return os.Remove(m.filePath)
+	m.beacons = nil
+	return m.backend.Clear()
 }
 
-// rotateIfNeeded 如果需要则轮转文件
-func (m *JSONLMemory) rotateIfNeeded() error {
-	info, err := os.Stat(m.filePath)
-	if err != nil {
-		return nil
+// SetMaxSize 设置最大文件大小（仅对文件后端有效）
+func (m *JSONLMemory) SetMaxSize(size int64) {
+	if fb, ok := m.backend.(*FileBackend); ok {
+		fb.SetMaxSize(size)
 	}
+}
 
-	if info.Size() < m.maxSize {
-		return nil
+// SetRetention 设置轮转段的总大小上限（字节）和保留时间（仅对文件后端有效）
+func (m *JSONLMemory) SetRetention(maxTotal int64, maxAge time.Duration) {
+	if fb, ok := m.backend.(*FileBackend); ok {
+		fb.SetRetention(maxTotal, maxAge)
 	}
+}
 
-	timestamp := time.Now().Format("20060102_150405")
-	backupPath := m.filePath + "." + timestamp + ".bak"
-
-	if err := os.Rename(m.filePath, backupPath); err != nil {
-		return fmt.Errorf("failed to rotate memory file: %w", err)
+// SetCompression 设置是否用 gzip 压缩轮转段（仅对文件后端有效）
+func (m *JSONLMemory) SetCompression(enabled bool) {
+	if fb, ok := m.backend.(*FileBackend); ok {
+		fb.SetCompression(enabled)
 	}
-
-	return nil
 }
 
-// SetMaxSize 设置最大文件大小
-func (m *JSONLMemory) SetMaxSize(size int64) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.maxSize = size
+// ReadRange 读取时间范围内指定类型的记忆（types 为空时不过滤）
+func (m *JSONLMemory) ReadRange(since, until time.Time, types ...string) ([]MemoryEntry, error) {
+	return m.backend.Query(Query{Types: types, Since: since, Until: until})
 }
 
-// SetBeacon 设置记忆信标
-func (m *JSONLMemory) SetBeacon(name string, metadata map[string]interface{}) error {
-	entry := MemoryEntry{
-		Timestamp: time.Now(),
-		Type:      "beacon",
-		TaskID:    name,
-		Content:   fmt.Sprintf("Beacon set: %s", name),
-	}
-
-	if metadata != nil {
-		dataBytes, _ := json.Marshal(metadata)
-		entry.Data = dataBytes
+// typesFilter 将单个类型参数转换为查询条件
+func typesFilter(entryType string) []string {
+	if entryType == "" {
+		return nil
 	}
-
-	return m.Write("beacon", name, entry.Content, metadata)
+	return []string{entryType}
 }
 
-// ReadSinceBeacon 读取从信标以来的记忆
-func (m *JSONLMemory) ReadSinceBeacon(beaconName string, entryType string) ([]MemoryEntry, error) {
-	allEntries, err := m.ReadAll()
+// ReadSinceBeacon 读取从信标以来的记忆，beaconRef 见 BeaconTime
+func (m *JSONLMemory) ReadSinceBeacon(beaconRef string, entryType string) ([]MemoryEntry, error) {
+	beaconTime, err := m.BeaconTime(beaconRef)
 	if err != nil {
 		return nil, err
 	}
-
-	var beaconTime time.Time
-	for _, entry := range allEntries {
-		if entry.Type == "beacon" && entry.TaskID == beaconName {
-			beaconTime = entry.Timestamp
-			break
-		}
-	}
-
-	if beaconTime.IsZero() {
-		return nil, fmt.Errorf("beacon '%s' not found", beaconName)
-	}
-
-	var result []MemoryEntry
-	for _, entry := range allEntries {
-		if entry.Timestamp.After(beaconTime) || entry.Timestamp.Equal(beaconTime) {
-			if entryType == "" || entry.Type == entryType {
-				result = append(result, entry)
-			}
-		}
-	}
-
-	return result, nil
+	return m.backend.Query(Query{Types: typesFilter(entryType), Since: beaconTime})
 }
 
-// ReadBetweenBeacons 读取两个信标之间的记忆
-func (m *JSONLMemory) ReadBetweenBeacons(startBeacon, endBeacon string, entryType string) ([]MemoryEntry, error) {
-	allEntries, err := m.ReadAll()
+// ReadBetweenBeacons 读取两个信标之间的记忆，beaconRef 见 BeaconTime；结束信标不存在时读到现在
+func (m *JSONLMemory) ReadBetweenBeacons(startRef, endRef string, entryType string) ([]MemoryEntry, error) {
+	startTime, err := m.BeaconTime(startRef)
 	if err != nil {
 		return nil, err
 	}
-
-	var startTime, endTime time.Time
-	for _, entry := range allEntries {
-		if entry.Type == "beacon" && entry.TaskID == startBeacon {
-			startTime = entry.Timestamp
-		}
-		if entry.Type == "beacon" && entry.TaskID == endBeacon {
-			endTime = entry.Timestamp
-		}
-	}
-
-	if startTime.IsZero() {
-		return nil, fmt.Errorf("start beacon '%s' not found", startBeacon)
+	endTime, err := m.BeaconTime(endRef)
+	if errors.Is(err, ErrBeaconNotFound) {
+		endTime, err = time.Now(), nil
 	}
-	if endTime.IsZero() {
-		endTime = time.Now()
-	}
-
-	var result []MemoryEntry
-	for _, entry := range allEntries {
-		if (entry.Timestamp.After(startTime) || entry.Timestamp.Equal(startTime)) &&
-			entry.Timestamp.Before(endTime) {
-			if entryType == "" || entry.Type == entryType {
-				result = append(result, entry)
-			}
-		}
+	if err != nil {
+		return nil, err
 	}
-
-	return result, nil
-}
-
-// ListBeacons 列出所有信标
-func (m *JSONLMemory) ListBeacons() ([]MemoryEntry, error) {
-	return m.ReadByType("beacon", 100)
+	return m.backend.Query(Query{Types: typesFilter(entryType), Since: startTime, Until: endTime})
 }
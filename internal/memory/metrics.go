@@ -0,0 +1,336 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeMetric 数值指标的记忆类型，Data 为 Metric
+const TypeMetric = "metric"
+
+// 指标错误
+var (
+	ErrInvalidMetric = errors.New("invalid metric")
+	ErrInvalidSeries = errors.New("invalid series query")
+)
+
+// 序列查询的默认和最大分桶数
+const (
+	DefaultSeriesBuckets = 60
+	MaxSeriesBuckets     = 10000
+)
+
+// metricNamePattern 指标名称：字母或下划线开头，可含字母、数字、"_"、"."、":"、"-"
+var metricNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.:-]{0,127}$`)
+
+// Metric 一个数值样本
+type Metric struct {
+	Name   string            `json:"name"`
+	Value  float64           `json:"value"`
+	Unit   string            `json:"unit,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ValidateMetricName 检查指标名称
+func ValidateMetricName(name string) error {
+	if !metricNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: bad name %q", ErrInvalidMetric, name)
+	}
+	return nil
+}
+
+// Validate 检查指标名称和数值
+func (mt Metric) Validate() error {
+	if err := ValidateMetricName(mt.Name); err != nil {
+		return err
+	}
+	if math.IsNaN(mt.Value) || math.IsInf(mt.Value, 0) {
+		return fmt.Errorf("%w: %s is not a finite number", ErrInvalidMetric, mt.Name)
+	}
+	for key := range mt.Labels {
+		if key == "" {
+			return fmt.Errorf("%w: empty label name", ErrInvalidMetric)
+		}
+	}
+	return nil
+}
+
+// String 指标的文本形式，如 "eth_price{pair=ETH/USDT}=3456.7 USD"
+func (mt Metric) String() string {
+	var b strings.Builder
+	b.WriteString(mt.Name)
+	if len(mt.Labels) > 0 {
+		b.WriteString("{")
+		b.WriteString(labelKey(mt.Labels))
+		b.WriteString("}")
+	}
+	b.WriteString("=")
+	b.WriteString(strconv.FormatFloat(mt.Value, 'g', -1, 64))
+	if mt.Unit != "" {
+		b.WriteString(" ")
+		b.WriteString(mt.Unit)
+	}
+	return b.String()
+}
+
+// labelKey 标签的规范形式（按名称排序的 k=v,k=v），用于区分序列
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + labels[key]
+	}
+	return strings.Join(parts, ",")
+}
+
+// WriteMetric 写入一个指标样本，taskID 为产生该数值的任务
+func (m *JSONLMemory) WriteMetric(taskID string, metric Metric) error {
+	if err := metric.Validate(); err != nil {
+		return err
+	}
+	return m.Write(TypeMetric, taskID, metric.String(), metric)
+}
+
+// parseMetric 从记忆中取出指标，不是有效指标时返回 false
+func parseMetric(entry MemoryEntry) (Metric, bool) {
+	var metric Metric
+	if entry.Type != TypeMetric || json.Unmarshal(entry.Data, &metric) != nil || metric.Name == "" {
+		return Metric{}, false
+	}
+	return metric, true
+}
+
+// matchLabels 判断 labels 是否包含 want 中的全部标签
+func matchLabels(labels, want map[string]string) bool {
+	for key, value := range want {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// SeriesQuery 指标序列查询条件
+type SeriesQuery struct {
+	Name        string
+	TaskID      string
+	Labels      map[string]string // 只返回包含这些标签的序列
+	Since       time.Time         // 包含；为零时从第一个样本开始
+	Until       time.Time         // 不包含；为零时到现在
+	StartBeacon string            // 从信标开始，引用格式见 BeaconTime
+	EndBeacon   string            // 到信标为止
+	Step        time.Duration     // 分桶宽度；为零时按 Buckets 均分
+	Buckets     int               // 分桶数，默认 DefaultSeriesBuckets
+}
+
+// SeriesPoint 一个分桶的聚合值，Time 为分桶起点
+type SeriesPoint struct {
+	Time  time.Time `json:"time"`
+	Count int       `json:"count"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Avg   float64   `json:"avg"`
+	First float64   `json:"first"`
+	Last  float64   `json:"last"`
+}
+
+// Series 同名、同标签的一条降采样序列，没有样本的分桶省略
+type Series struct {
+	Name    string            `json:"name"`
+	Unit    string            `json:"unit,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Samples int               `json:"samples"`
+	Points  []SeriesPoint     `json:"points"`
+}
+
+// SeriesResult 序列查询结果
+type SeriesResult struct {
+	Name   string    `json:"name"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Step   string    `json:"step"`
+	Series []Series  `json:"series"`
+}
+
+// MetricSeries 查询指标在时间范围（或两个信标之间）的降采样序列
+//
+// 分桶从范围起点开始对齐，每个分桶给出样本数、最小、最大、平均、首个和最后一个值；
+// 同名指标按标签分为多条序列。
+func (m *JSONLMemory) MetricSeries(q SeriesQuery) (*SeriesResult, error) {
+	if err := ValidateMetricName(q.Name); err != nil {
+		return nil, err
+	}
+	if q.Step < 0 || q.Buckets < 0 || q.Buckets > MaxSeriesBuckets {
+		return nil, fmt.Errorf("%w: buckets must be 1-%d", ErrInvalidSeries, MaxSeriesBuckets)
+	}
+
+	window := Search{Since: q.Since, Until: q.Until, StartBeacon: q.StartBeacon, EndBeacon: q.EndBeacon}
+	if err := m.resolveWindow(&window); err != nil {
+		return nil, err
+	}
+	since, until := window.Since, window.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	if !since.IsZero() && !since.Before(until) {
+		return nil, fmt.Errorf("%w: since must be before until", ErrInvalidSeries)
+	}
+
+	entries, err := m.backend.Query(Query{Types: []string{TypeMetric}, TaskID: q.TaskID, Since: since, Until: until})
+	if err != nil {
+		return nil, err
+	}
+
+	type sample struct {
+		at    time.Time
+		value float64
+	}
+	type group struct {
+		series  Series
+		samples []sample
+	}
+	groups := make(map[string]*group)
+	var keys []string
+	for _, entry := range entries {
+		metric, ok := parseMetric(entry)
+		if !ok || metric.Name != q.Name || !matchLabels(metric.Labels, q.Labels) {
+			continue
+		}
+		key := labelKey(metric.Labels)
+		g := groups[key]
+		if g == nil {
+			g = &group{series: Series{Name: metric.Name, Labels: metric.Labels}}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		if metric.Unit != "" {
+			g.series.Unit = metric.Unit
+		}
+		g.samples = append(g.samples, sample{entry.Timestamp, metric.Value})
+	}
+
+	if since.IsZero() {
+		since = until
+		for _, g := range groups {
+			if first := g.samples[0].at; first.Before(since) {
+				since = first
+			}
+		}
+	}
+	step := q.Step
+	if step == 0 {
+		buckets := q.Buckets
+		if buckets == 0 {
+			buckets = DefaultSeriesBuckets
+		}
+		step = until.Sub(since) / time.Duration(buckets)
+		if rem := step % time.Second; rem != 0 || step == 0 {
+			step += time.Second - rem
+		}
+	}
+	if n := until.Sub(since) / step; n >= MaxSeriesBuckets {
+		return nil, fmt.Errorf("%w: step %s gives more than %d buckets", ErrInvalidSeries, step, MaxSeriesBuckets)
+	}
+
+	result := &SeriesResult{Name: q.Name, Since: since, Until: until, Step: step.String(), Series: []Series{}}
+	sort.Strings(keys)
+	for _, key := range keys {
+		g := groups[key]
+		g.series.Samples = len(g.samples)
+		g.series.Points = []SeriesPoint{}
+		var point *SeriesPoint
+		var sum float64
+		for _, s := range g.samples {
+			start := since.Add(s.at.Sub(since) / step * step)
+			if point == nil || !point.Time.Equal(start) {
+				if point != nil {
+					point.Avg = sum / float64(point.Count)
+				}
+				g.series.Points = append(g.series.Points, SeriesPoint{Time: start, Min: s.value, Max: s.value, First: s.value})
+				point, sum = &g.series.Points[len(g.series.Points)-1], 0
+			}
+			point.Count++
+			point.Min = math.Min(point.Min, s.value)
+			point.Max = math.Max(point.Max, s.value)
+			point.Last = s.value
+			sum += s.value
+		}
+		if point != nil {
+			point.Avg = sum / float64(point.Count)
+		}
+		result.Series = append(result.Series, g.series)
+	}
+	return result, nil
+}
+
+// MetricInfo 一条指标序列的概况
+type MetricInfo struct {
+	Name      string            `json:"name"`
+	Unit      string            `json:"unit,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Tasks     []string          `json:"tasks,omitempty"`
+	Samples   int               `json:"samples"`
+	First     time.Time         `json:"first"`
+	Last      time.Time         `json:"last"`
+	LastValue float64           `json:"last_value"`
+}
+
+// ListMetrics 列出时间范围内（s 中的 Types 和分页字段被忽略）出现过的指标序列，按名称和标签排序
+func (m *JSONLMemory) ListMetrics(s Search) ([]MetricInfo, error) {
+	if err := m.resolveWindow(&s); err != nil {
+		return nil, err
+	}
+	entries, err := m.backend.Query(Query{Types: []string{TypeMetric}, TaskID: s.TaskID, Since: s.Since, Until: s.Until})
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]*MetricInfo)
+	tasks := make(map[string]map[string]bool)
+	for _, entry := range entries {
+		metric, ok := parseMetric(entry)
+		if !ok {
+			continue
+		}
+		key := metric.Name + "{" + labelKey(metric.Labels) + "}"
+		info := infos[key]
+		if info == nil {
+			info = &MetricInfo{Name: metric.Name, Labels: metric.Labels, First: entry.Timestamp}
+			infos[key] = info
+			tasks[key] = make(map[string]bool)
+		}
+		if metric.Unit != "" {
+			info.Unit = metric.Unit
+		}
+		if entry.TaskID != "" && !tasks[key][entry.TaskID] {
+			tasks[key][entry.TaskID] = true
+			info.Tasks = append(info.Tasks, entry.TaskID)
+		}
+		info.Samples++
+		info.Last = entry.Timestamp
+		info.LastValue = metric.Value
+	}
+
+	keys := make([]string, 0, len(infos))
+	for key := range infos {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	list := make([]MetricInfo, 0, len(keys))
+	for _, key := range keys {
+		sort.Strings(infos[key].Tasks)
+		list = append(list, *infos[key])
+	}
+	return list, nil
+}
@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor 分页游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// 检索每页的默认和最大条数
+const (
+	DefaultSearchLimit = 100
+	MaxSearchLimit     = 1000
+)
+
+// Search 记忆检索条件，零值字段不过滤
+type Search struct {
+	Types       []string
+	TaskID      string
+	Since       time.Time // 包含
+	Until       time.Time // 不包含
+	StartBeacon string    // 从信标开始，引用格式见 BeaconTime
+	EndBeacon   string    // 到信标为止
+	Contains    string    // 内容包含该子串（不区分大小写）
+	Pattern     *regexp.Regexp
+	Limit       int    // 每页条数，默认 DefaultSearchLimit，最大 MaxSearchLimit
+	Desc        bool   // 从新到旧
+	Latest      bool   // 取最新的一页，页内从旧到新；游标与 Desc 相同，指向更早的一页
+	Cursor      string // 上一页的 NextCursor
+	Zoom        string // LevelHour 或 LevelDay 时返回该层级的摘要而不是原始记忆（Types 被忽略）
+}
+
+// Page 一页检索结果
+type Page struct {
+	Entries    []MemoryEntry
+	NextCursor string    // 为空表示没有更多
+	Since      time.Time // 解析信标后的实际范围，零值表示不限
+	Until      time.Time
+}
+
+// Search 按条件分页检索记忆，结果按请求的方向排列
+//
+// 游标记录上一页最后一条的时间戳和该时间戳已返回的条数，下一页从这里继续，
+// 与存储后端无关；同一时间戳的条目按写入顺序排列。
+func (m *JSONLMemory) Search(s Search) (*Page, error) {
+	if err := m.resolveWindow(&s); err != nil {
+		return nil, err
+	}
+	if s.Latest {
+		s.Desc = true
+	}
+	q := Query{Types: s.Types, TaskID: s.TaskID, Since: s.Since, Until: s.Until, Newest: s.Desc}
+	page := &Page{Entries: []MemoryEntry{}, Since: q.Since, Until: q.Until}
+
+	// 摘要不属于单个任务，按任务过滤时取包含该任务的摘要；
+	// 时间范围按摘要覆盖时段的起点过滤，时间戳（写入时间）不早于起点，Since 仍可作为下界
+	var byTask string
+	var zoomUntil time.Time
+	if s.Zoom != "" {
+		summaryType, ok := SummaryType(s.Zoom)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidZoom, s.Zoom)
+		}
+		q.Types = []string{summaryType}
+		byTask, q.TaskID = q.TaskID, ""
+		zoomUntil, q.Until = q.Until, time.Time{}
+	}
+
+	if s.Contains != "" || s.Pattern != nil || byTask != "" || s.Zoom != "" {
+		contains := strings.ToLower(s.Contains)
+		q.Match = func(e MemoryEntry) bool {
+			if contains != "" && !strings.Contains(strings.ToLower(e.Content), contains) {
+				return false
+			}
+			if s.Zoom != "" {
+				start := SummaryStart(e)
+				if start.Before(s.Since) || (!zoomUntil.IsZero() && !start.Before(zoomUntil)) {
+					return false
+				}
+			}
+			if byTask != "" {
+				var summary Summary
+				if json.Unmarshal(e.Data, &summary) != nil || summary.ByTask[byTask] == 0 {
+					return false
+				}
+			}
+			return s.Pattern == nil || s.Pattern.MatchString(e.Content)
+		}
+	}
+
+	limit := s.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	// 从游标继续：时间范围收缩到游标处，再跳过该时间戳已返回的条目
+	var at time.Time
+	var skip int
+	if s.Cursor != "" {
+		var err error
+		if at, skip, err = ParseCursor(s.Cursor); err != nil {
+			return nil, err
+		}
+		if s.Desc {
+			if end := at.Add(time.Nanosecond); q.Until.IsZero() || end.Before(q.Until) {
+				q.Until = end
+			}
+		} else if at.After(q.Since) {
+			q.Since = at
+		}
+	}
+
+	q.Limit = limit + skip + 1
+	entries, err := m.backend.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	if s.Desc {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	if s.Cursor != "" {
+		n := 0
+		for n < skip && n < len(entries) && entries[n].Timestamp.Equal(at) {
+			n++
+		}
+		entries = entries[n:]
+	}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1].Timestamp
+		seen := 0
+		for _, e := range entries {
+			if e.Timestamp.Equal(last) {
+				seen++
+			}
+		}
+		if s.Cursor != "" && last.Equal(at) {
+			seen += skip
+		}
+		page.NextCursor = FormatCursor(last, seen)
+	}
+	if s.Latest {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	if len(entries) > 0 {
+		page.Entries = entries
+	}
+	return page, nil
+}
+
+// FormatCursor 游标格式：<Unix 纳秒>-<该时间戳已返回的条数>
+func FormatCursor(t time.Time, seen int) string {
+	return fmt.Sprintf("%d-%d", t.UnixNano(), seen)
+}
+
+// maxCursorSeen 游标中同一时间戳已返回条数的上限，防止伪造的游标让查询不受限制
+const maxCursorSeen = 100 * MaxSearchLimit
+
+// ParseCursor 解析 FormatCursor 生成的游标
+func ParseCursor(cursor string) (time.Time, int, error) {
+	tsPart, seenPart, ok := strings.Cut(cursor, "-")
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+	}
+	ts, err := strconv.ParseInt(tsPart, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+	}
+	seen, err := strconv.Atoi(seenPart)
+	if err != nil || seen < 1 || seen > maxCursorSeen {
+		return time.Time{}, 0, fmt.Errorf("%w: %s", ErrInvalidCursor, cursor)
+	}
+	return time.Unix(0, ts), seen, nil
+}
@@ -0,0 +1,329 @@
+package memory
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxTotal 记忆文件（含轮转段）的默认总大小上限
+const defaultMaxTotal = 100 * 1024 * 1024
+
+// segment 一段记忆文件；轮转后的段只读
+//
+// 原始段（.bak）的索引在首次查询时建立并缓存；压缩段（.bak.gz）只保留 sidecar 摘要，
+// 查询时先用摘要判断能否命中，需要时才解压。
+type segment struct {
+	path  string
+	size  int64        // 磁盘占用（压缩段包括 sidecar）
+	last  time.Time    // 最后写入时间：原始段取文件修改时间，压缩段取最后一条记忆的时间
+	index *memoryIndex // 原始段的索引
+	meta  *segmentMeta // 压缩段的 sidecar 摘要
+
+	archiveFailed bool // 压缩失败后不再重试，保留原始段
+}
+
+// loadSegments 找出已轮转的记忆文件，按轮转时间从旧到新排列
+//
+// 同时存在 .bak 和 .bak.gz 说明上次压缩没有完成，丢弃压缩文件并保留原始段。
+func loadSegments(filePath string) ([]*segment, error) {
+	raw, err := filepath.Glob(filePath + ".*.bak")
+	if err != nil {
+		return nil, err
+	}
+	archived, err := filepath.Glob(filePath + ".*.bak" + archiveSuffix)
+	if err != nil {
+		return nil, err
+	}
+	removeTempArchives(filePath)
+
+	rawSet := make(map[string]bool, len(raw))
+	for _, path := range raw {
+		rawSet[path] = true
+	}
+
+	var segments []*segment
+	for _, path := range raw {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, &segment{path: path, size: info.Size(), last: info.ModTime()})
+	}
+	for _, path := range archived {
+		if rawSet[strings.TrimSuffix(path, archiveSuffix)] {
+			removeArchive(path)
+			continue
+		}
+		s, err := loadArchive(path)
+		if err != nil {
+			log.Printf("Warning: Skipping memory archive %s: %v", filepath.Base(path), err)
+			continue
+		}
+		segments = append(segments, s)
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		ti, si := segments[i].order()
+		tj, sj := segments[j].order()
+		if ti != tj {
+			return ti < tj
+		}
+		return si < sj
+	})
+	return segments, nil
+}
+
+// order 从文件名解析轮转时间和同一秒内的序号（<file>.<时间>[_序号].bak[.gz]）
+func (s *segment) order() (string, int) {
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(s.path), archiveSuffix), ".bak")
+	stamp := name[strings.LastIndex(name, ".")+1:]
+	// 时间本身含有下划线，其后的 _N 才是序号
+	if len(stamp) > len(rotateLayout) {
+		if seq, err := strconv.Atoi(stamp[len(rotateLayout)+1:]); err == nil {
+			return stamp[:len(rotateLayout)], seq
+		}
+	}
+	return stamp, 0
+}
+
+// segmentIndex 返回原始段的索引，必要时建立
+func (s *segment) segmentIndex() (*memoryIndex, error) {
+	if s.index == nil {
+		index, err := buildIndex(s.path)
+		if err != nil {
+			return nil, err
+		}
+		s.index = index
+	}
+	return s.index, nil
+}
+
+// query 在段内查询
+func (s *segment) query(q Query) ([]MemoryEntry, error) {
+	if s.meta != nil {
+		if !s.meta.mayMatch(q) {
+			return nil, nil
+		}
+		return queryArchive(s.path, q)
+	}
+	index, err := s.segmentIndex()
+	if err != nil {
+		return nil, err
+	}
+	return queryFile(s.path, index, q)
+}
+
+// span 段内最早和最晚的时间戳、条数以及时间是否递增
+func (s *segment) span() (first, last time.Time, count int, sorted bool, err error) {
+	if s.meta != nil {
+		return s.meta.First, s.meta.Last, s.meta.Count, !s.meta.Unsorted, nil
+	}
+	index, err := s.segmentIndex()
+	if err != nil {
+		return time.Time{}, time.Time{}, 0, false, err
+	}
+	return index.first, index.last, len(index.entries), index.sorted, nil
+}
+
+// stats 段内各类型的条数
+func (s *segment) stats() (map[string]int, error) {
+	if s.meta != nil {
+		return s.meta.Types, nil
+	}
+	index, err := s.segmentIndex()
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]int, len(index.byType))
+	for typ, positions := range index.byType {
+		stats[typ] = len(positions)
+	}
+	return stats, nil
+}
+
+// remove 删除段的文件
+func (s *segment) remove() error {
+	if s.meta != nil {
+		return removeArchive(s.path)
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// allSegments 轮转段加上当前文件，从旧到新（调用方需持有 b.mu）
+func (b *FileBackend) allSegments() []*segment {
+	active := &segment{path: b.filePath, index: b.index, last: time.Now()}
+	return append(append([]*segment(nil), b.segments...), active)
+}
+
+// queryLocked 将轮转段和当前文件视为一个按时间排列的日志进行查询（调用方需持有 b.mu）
+//
+// 按查询方向逐段读取，取够 Limit 条即停止；Since 之前结束的段直接跳过。
+// 段之间时间重叠或段内时间无序（如导入了旧记忆）时改为合并排序。
+func (b *FileBackend) queryLocked(q Query) ([]MemoryEntry, error) {
+	segments := b.allSegments()
+	ordered, err := orderedSegments(segments)
+	if err != nil {
+		return nil, err
+	}
+	if !ordered {
+		return mergeSegments(segments, q)
+	}
+
+	var parts [][]MemoryEntry
+	remaining := q.Limit
+	read := func(s *segment) (bool, error) {
+		if !q.Since.IsZero() && s.last.Before(q.Since) {
+			return false, nil
+		}
+		sub := q
+		sub.Limit = remaining
+		entries, err := s.query(sub)
+		if err != nil {
+			return false, err
+		}
+		parts = append(parts, entries)
+		if q.Limit > 0 {
+			remaining -= len(entries)
+			return remaining <= 0, nil
+		}
+		return false, nil
+	}
+
+	if q.Newest {
+		for i := len(segments) - 1; i >= 0; i-- {
+			done, err := read(segments[i])
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+		}
+		// 反向读取的段恢复为时间正序
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+	} else {
+		for _, s := range segments {
+			done, err := read(s)
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+		}
+	}
+
+	var result []MemoryEntry
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return result, nil
+}
+
+// orderedSegments 判断各段时间是否递增且互不重叠
+func orderedSegments(segments []*segment) (bool, error) {
+	var prev time.Time
+	for _, s := range segments {
+		first, last, count, sorted, err := s.span()
+		if err != nil {
+			return false, err
+		}
+		if count == 0 {
+			continue
+		}
+		if !sorted || first.Before(prev) {
+			return false, nil
+		}
+		prev = last
+	}
+	return true, nil
+}
+
+// mergeSegments 读取全部段中的匹配条目，按时间排序后截取 Limit 条
+func mergeSegments(segments []*segment, q Query) ([]MemoryEntry, error) {
+	all := q
+	all.Limit = 0
+	var result []MemoryEntry
+	for _, s := range segments {
+		entries, err := s.query(all)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entries...)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	if q.Limit > 0 && len(result) > q.Limit {
+		if q.Newest {
+			return result[len(result)-q.Limit:], nil
+		}
+		return result[:q.Limit], nil
+	}
+	return result, nil
+}
+
+// sealLocked 当前文件轮转为只读段，沿用已建立的索引（调用方需持有 b.mu）
+func (b *FileBackend) sealLocked(path string, info os.FileInfo) {
+	s := &segment{path: path, size: info.Size(), last: info.ModTime()}
+	if b.index.size == info.Size() {
+		s.index = b.index
+	}
+	b.segments = append(b.segments, s)
+	b.index = newMemoryIndex()
+	b.startArchiverLocked()
+}
+
+// enforceRetentionLocked 删除超出总大小或保留时间的最旧轮转段，当前文件不受影响（调用方需持有 b.mu）
+func (b *FileBackend) enforceRetentionLocked() {
+	total := b.index.size
+	for _, s := range b.segments {
+		total += s.size
+	}
+
+	now := time.Now()
+	for len(b.segments) > 0 {
+		oldest := b.segments[0]
+		expired := b.maxAge > 0 && now.Sub(oldest.last) > b.maxAge
+		over := b.maxTotal > 0 && total > b.maxTotal
+		if !expired && !over {
+			return
+		}
+		if err := oldest.remove(); err != nil {
+			log.Printf("Warning: Failed to remove memory segment %s: %v", filepath.Base(oldest.path), err)
+			return
+		}
+		total -= oldest.size
+		b.segments = b.segments[1:]
+	}
+}
+
+// SetRetention 设置轮转段的保留策略：总大小上限（字节）和最长保留时间，0 表示不限
+func (b *FileBackend) SetRetention(maxTotal int64, maxAge time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.maxTotal = maxTotal
+	b.maxAge = maxAge
+	b.enforceRetentionLocked()
+}
+
+// clearSegmentsLocked 删除全部轮转段（调用方需持有 b.mu）
+func (b *FileBackend) clearSegmentsLocked() error {
+	for _, s := range b.segments {
+		if err := s.remove(); err != nil {
+			return fmt.Errorf("failed to remove memory segment: %w", err)
+		}
+	}
+	b.segments = nil
+	return nil
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultTailBuffer 订阅的默认缓冲条数，订阅者跟不上时多出的条目被丢弃并计数
+const DefaultTailBuffer = 256
+
+// TailFilter 订阅条件，零值字段不过滤
+type TailFilter struct {
+	Types    []string
+	TaskID   string
+	Contains string // 内容包含该子串（不区分大小写）
+	Pattern  *regexp.Regexp
+}
+
+// Match 判断条目是否满足订阅条件
+func (f TailFilter) Match(entry MemoryEntry) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == entry.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.TaskID != "" && entry.TaskID != f.TaskID {
+		return false
+	}
+	if f.Contains != "" && !strings.Contains(strings.ToLower(entry.Content), strings.ToLower(f.Contains)) {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(entry.Content)
+}
+
+// Subscription 记忆写入的订阅，C 在 Close 后关闭
+type Subscription struct {
+	C <-chan MemoryEntry
+
+	ch      chan MemoryEntry
+	filter  TailFilter
+	dropped atomic.Int64
+	memory  *JSONLMemory
+}
+
+// Subscribe 订阅之后写入的、满足条件的记忆（导入的条目不推送）
+//
+// 推送不会阻塞写入：缓冲已满时条目被丢弃，通过 TakeDropped 得知丢弃的条数。
+func (m *JSONLMemory) Subscribe(filter TailFilter, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultTailBuffer
+	}
+	ch := make(chan MemoryEntry, buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, memory: m}
+
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	if m.subscribers == nil {
+		m.subscribers = make(map[*Subscription]struct{})
+	}
+	m.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Close 取消订阅，可重复调用
+func (s *Subscription) Close() {
+	m := s.memory
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	if _, ok := m.subscribers[s]; ok {
+		delete(m.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// TakeDropped 返回自上次调用以来因缓冲已满而丢弃的条数
+func (s *Subscription) TakeDropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// Subscribers 当前的订阅数
+func (m *JSONLMemory) Subscribers() int {
+	m.subMu.RLock()
+	defer m.subMu.RUnlock()
+	return len(m.subscribers)
+}
+
+// publish 将写入成功的条目交给监听函数和订阅者（调用方不能持有 m.mu）
+func (m *JSONLMemory) publish(entry MemoryEntry) {
+	m.subMu.RLock()
+	defer m.subMu.RUnlock()
+
+	if m.listener != nil {
+		m.listener(entry)
+	}
+	for sub := range m.subscribers {
+		if !sub.filter.Match(entry) {
+			continue
+		}
+		select {
+		case sub.ch <- entry:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
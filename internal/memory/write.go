@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 外部写入的错误
+var (
+	ErrInvalidEntry = errors.New("invalid memory entry")
+	ErrReservedType = errors.New("reserved memory type")
+)
+
+// 外部写入的限制
+const (
+	MaxBatchEntries = 1000
+	maxEntryContent = 64 * 1024
+	maxEntryData    = 256 * 1024
+)
+
+// entryTypePattern 记忆类型：小写字母开头，可含小写字母、数字和 "_"
+var entryTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// reservedPrefixes 由小脑自己写入的类型前缀，外部不能写入
+var reservedPrefixes = []string{"task_", "decision_", "beacon", "summary_"}
+
+// NewEntry 外部（大脑）写入的一条记忆，时间戳为写入时间
+type NewEntry struct {
+	Type    string          `json:"type"`
+	TaskID  string          `json:"task_id,omitempty"`
+	Content string          `json:"content"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// ValidateEntryType 检查外部写入的记忆类型：格式正确且不是小脑保留的类型
+func ValidateEntryType(entryType string) error {
+	if !entryTypePattern.MatchString(entryType) {
+		return fmt.Errorf("%w: type %q must match %s", ErrInvalidEntry, entryType, entryTypePattern)
+	}
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(entryType, prefix) {
+			return fmt.Errorf("%w: %q is written by cerebellum", ErrReservedType, entryType)
+		}
+	}
+	return nil
+}
+
+// Validate 检查类型、内容和数据；metric 类型的数据需要是有效的 Metric，内容为空时自动生成
+func (e *NewEntry) Validate() error {
+	if err := ValidateEntryType(e.Type); err != nil {
+		return err
+	}
+	if len(e.Content) > maxEntryContent {
+		return fmt.Errorf("%w: content longer than %d bytes", ErrInvalidEntry, maxEntryContent)
+	}
+	if len(e.Data) > maxEntryData {
+		return fmt.Errorf("%w: data longer than %d bytes", ErrInvalidEntry, maxEntryData)
+	}
+	if bytes.Equal(bytes.TrimSpace(e.Data), []byte("null")) {
+		e.Data = nil
+	}
+
+	if e.Type == TypeMetric {
+		var metric Metric
+		if err := json.Unmarshal(e.Data, &metric); err != nil {
+			return fmt.Errorf("%w: metric data: %v", ErrInvalidMetric, err)
+		}
+		if err := metric.Validate(); err != nil {
+			return err
+		}
+		if e.Content == "" {
+			e.Content = metric.String()
+		}
+	}
+	if e.Content == "" && len(e.Data) == 0 {
+		return fmt.Errorf("%w: content or data is required", ErrInvalidEntry)
+	}
+	return nil
+}
+
+// WriteBatch 校验并写入一批外部记忆，任何一条无效时都不写入
+//
+// 返回已写入的条目；存储出错时返回出错前已写入的部分。
+func (m *JSONLMemory) WriteBatch(entries []NewEntry) ([]MemoryEntry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no entries", ErrInvalidEntry)
+	}
+	if len(entries) > MaxBatchEntries {
+		return nil, fmt.Errorf("%w: more than %d entries", ErrInvalidEntry, MaxBatchEntries)
+	}
+	for i := range entries {
+		if err := entries[i].Validate(); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+
+	m.mu.Lock()
+	written := make([]MemoryEntry, 0, len(entries))
+	var err error
+	for _, e := range entries {
+		entry := MemoryEntry{
+			Timestamp: time.Now(),
+			Type:      e.Type,
+			TaskID:    e.TaskID,
+			Content:   e.Content,
+			Data:      e.Data,
+		}
+		if err = m.backend.Append(entry); err != nil {
+			err = fmt.Errorf("failed to write memory: %w", err)
+			break
+		}
+		written = append(written, entry)
+	}
+	m.mu.Unlock()
+
+	for _, entry := range written {
+		m.publish(entry)
+	}
+	return written, err
+}
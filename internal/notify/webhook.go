@@ -0,0 +1,508 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 重试退避参数
+const (
+	initialBackoff = 5 * time.Second
+	maxBackoff     = 10 * time.Minute
+	maxLogEntries  = 5000
+)
+
+// Target 接收通知的 webhook
+type Target struct {
+	Name        string
+	URL         string
+	Secret      string   // 非空时对请求体做 HMAC-SHA256 签名
+	Namespaces  []string // 为空表示接收所有命名空间的通知
+	MaxAttempts int
+	Timeout     time.Duration
+}
+
+// accepts 判断目标是否订阅了该命名空间
+func (t Target) accepts(namespace string) bool {
+	if len(t.Namespaces) == 0 {
+		return true
+	}
+	for _, ns := range t.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery 待投递的一条通知（持久化在 outbox 中）
+type Delivery struct {
+	ID          string          `json:"id"`
+	EventID     string          `json:"event_id"`
+	Event       string          `json:"event"`
+	Webhook     string          `json:"webhook"`
+	Namespace   string          `json:"namespace"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// Attempt 投递日志中的一次尝试
+type Attempt struct {
+	DeliveryID string    `json:"delivery_id"`
+	EventID    string    `json:"event_id"`
+	Event      string    `json:"event"`
+	Webhook    string    `json:"webhook"`
+	Namespace  string    `json:"namespace"`
+	Attempt    int       `json:"attempt"`
+	Time       time.Time `json:"time"`
+	DurationMs int64     `json:"duration_ms"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Result     string    `json:"result"` // delivered | retrying | failed
+}
+
+// LogQuery 投递日志查询条件，零值字段不过滤
+type LogQuery struct {
+	Namespace string
+	Webhook   string
+	Result    string
+	Since     time.Time
+	Limit     int
+}
+
+// Notifier 通过 webhook 向大脑投递通知
+//
+// 通知先写入 outbox 目录再异步投递，进程重启后未完成的投递会继续重试。
+type Notifier struct {
+	targets   map[string]Target
+	outboxDir string
+	logPath   string
+	client    *http.Client
+	pending   map[string]*Delivery
+	logLines  int
+	seq       int64
+	mu        sync.Mutex
+	wake      chan struct{}
+}
+
+// New 创建通知器并加载 outbox 中未完成的投递
+func New(dataDir string, targets []Target) (*Notifier, error) {
+	outboxDir := filepath.Join(dataDir, "outbox")
+	if err := os.MkdirAll(outboxDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	n := &Notifier{
+		targets:   make(map[string]Target),
+		outboxDir: outboxDir,
+		logPath:   filepath.Join(dataDir, "deliveries.jsonl"),
+		client:    &http.Client{},
+		pending:   make(map[string]*Delivery),
+		wake:      make(chan struct{}, 1),
+	}
+	for _, t := range targets {
+		n.targets[t.Name] = t
+	}
+
+	if err := n.loadOutbox(); err != nil {
+		return nil, err
+	}
+	if attempts, err := n.readLog(); err == nil {
+		n.logLines = len(attempts)
+	}
+	return n, nil
+}
+
+// loadOutbox 读取 outbox 中的投递，已删除的 webhook 的投递被丢弃
+func (n *Notifier) loadOutbox() error {
+	files, err := filepath.Glob(filepath.Join(n.outboxDir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list outbox: %w", err)
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var d Delivery
+		if err := json.Unmarshal(data, &d); err != nil {
+			log.Printf("Warning: Skipping corrupt outbox file %s: %v", filepath.Base(path), err)
+			continue
+		}
+		if _, ok := n.targets[d.Webhook]; !ok {
+			log.Printf("Warning: Dropping delivery %s for removed webhook %s", d.ID, d.Webhook)
+			os.Remove(path)
+			continue
+		}
+		n.pending[d.ID] = &d
+	}
+	if len(n.pending) > 0 {
+		log.Printf("✓ Resuming %d pending webhook deliveries", len(n.pending))
+	}
+	return nil
+}
+
+// Enqueue 为订阅了该命名空间的每个 webhook 写入一条投递
+func (n *Notifier) Enqueue(namespace, event string, payload interface{}) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	n.seq++
+	eventID := fmt.Sprintf("evt-%d-%d", now.UnixNano(), n.seq)
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	for _, t := range n.targets {
+		if !t.accepts(namespace) {
+			continue
+		}
+		d := &Delivery{
+			ID:          fmt.Sprintf("%s-%s", eventID, t.Name),
+			EventID:     eventID,
+			Event:       event,
+			Webhook:     t.Name,
+			Namespace:   namespace,
+			Payload:     body,
+			CreatedAt:   now,
+			NextAttempt: now,
+		}
+		if err := n.persist(d); err != nil {
+			return err
+		}
+		n.pending[d.ID] = d
+	}
+
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run 持续投递到期的通知（阻塞）
+func (n *Notifier) Run() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-n.wake:
+		}
+
+		for _, d := range n.due(time.Now()) {
+			n.deliver(d)
+		}
+
+		timer.Reset(n.untilNext(time.Now()))
+	}
+}
+
+// due 返回到期的投递（按创建时间排序）
+func (n *Notifier) due(now time.Time) []*Delivery {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var due []*Delivery
+	for _, d := range n.pending {
+		if !d.NextAttempt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].CreatedAt.Before(due[j].CreatedAt) })
+	return due
+}
+
+// untilNext 距离下一次投递的时间，没有待投递时等待唤醒
+func (n *Notifier) untilNext(now time.Time) time.Duration {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	next := time.Hour
+	for _, d := range n.pending {
+		if wait := d.NextAttempt.Sub(now); wait < next {
+			next = wait
+		}
+	}
+	if next < 0 {
+		next = 0
+	}
+	return next
+}
+
+// deliver 投递一次并根据结果完成、重试或放弃
+func (n *Notifier) deliver(d *Delivery) {
+	n.mu.Lock()
+	target, ok := n.targets[d.Webhook]
+	n.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	started := time.Now()
+	status, err := n.send(target, d)
+	attempt := Attempt{
+		DeliveryID: d.ID,
+		EventID:    d.EventID,
+		Event:      d.Event,
+		Webhook:    d.Webhook,
+		Namespace:  d.Namespace,
+		Attempt:    d.Attempts + 1,
+		Time:       started,
+		DurationMs: time.Since(started).Milliseconds(),
+		StatusCode: status,
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	d.Attempts++
+	switch {
+	case err == nil:
+		attempt.Result = "delivered"
+		n.finish(d)
+	case !retryable(status) || d.Attempts >= target.MaxAttempts:
+		attempt.Result = "failed"
+		attempt.Error = err.Error()
+		log.Printf("Warning: Webhook %s gave up on delivery %s after %d attempts: %v", d.Webhook, d.ID, d.Attempts, err)
+		n.finish(d)
+	default:
+		attempt.Result = "retrying"
+		attempt.Error = err.Error()
+		d.LastError = err.Error()
+		d.NextAttempt = time.Now().Add(backoff(d.Attempts))
+		if err := n.persist(d); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	n.appendLog(attempt)
+}
+
+// send 发送请求，非 2xx 响应视为失败
+func (n *Notifier) send(target Target, d *Delivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, target.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Cerebellum-Webhook/1.0")
+	req.Header.Set("X-Cerebellum-Event", d.Event)
+	req.Header.Set("X-Cerebellum-Delivery", d.ID)
+	req.Header.Set("X-Cerebellum-Namespace", d.Namespace)
+	req.Header.Set("X-Cerebellum-Timestamp", timestamp)
+	if target.Secret != "" {
+		req.Header.Set("X-Cerebellum-Signature", "sha256="+Sign(target.Secret, timestamp, d.Payload))
+	}
+
+	client := *n.client
+	client.Timeout = target.Timeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body) 的十六进制
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryable 网络错误、5xx、408 和 429 可以重试，其他 4xx 说明请求本身不被接受
+func retryable(status int) bool {
+	return status == 0 || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// backoff 第 n 次失败后的等待时间：指数增长并带 ±20% 抖动，不超过 maxBackoff
+func backoff(attempts int) time.Duration {
+	d := initialBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	d += time.Duration(rand.Int63n(int64(d)/5*2+1)) - d/5
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// persist 将投递写入 outbox（调用方需持有 n.mu）
+func (n *Notifier) persist(d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+	path := n.outboxPath(d.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return nil
+}
+
+// finish 从 outbox 移除已完成或放弃的投递（调用方需持有 n.mu）
+func (n *Notifier) finish(d *Delivery) {
+	delete(n.pending, d.ID)
+	os.Remove(n.outboxPath(d.ID))
+}
+
+// outboxPath 投递在 outbox 中的文件路径
+func (n *Notifier) outboxPath(id string) string {
+	return filepath.Join(n.outboxDir, id+".json")
+}
+
+// appendLog 追加投递日志，超过上限时只保留较新的一半（调用方需持有 n.mu）
+func (n *Notifier) appendLog(attempt Attempt) {
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(n.logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Warning: Failed to open delivery log: %v", err)
+		return
+	}
+	file.Write(append(data, '\n'))
+	file.Close()
+	n.logLines++
+
+	if n.logLines > maxLogEntries {
+		attempts, err := n.readLog()
+		if err != nil {
+			return
+		}
+		keep := attempts[len(attempts)-maxLogEntries/2:]
+		var buf bytes.Buffer
+		for _, a := range keep {
+			line, _ := json.Marshal(a)
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		tmp := n.logPath + ".tmp"
+		if err := os.WriteFile(tmp, buf.Bytes(), 0644); err == nil && os.Rename(tmp, n.logPath) == nil {
+			n.logLines = len(keep)
+		}
+	}
+}
+
+// readLog 读取全部投递日志（按时间正序）
+func (n *Notifier) readLog() ([]Attempt, error) {
+	file, err := os.Open(n.logPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var attempts []Attempt
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var a Attempt
+		if err := json.Unmarshal(scanner.Bytes(), &a); err != nil {
+			continue
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, scanner.Err()
+}
+
+// Log 查询投递日志，结果按时间倒序
+func (n *Notifier) Log(q LogQuery) ([]Attempt, error) {
+	n.mu.Lock()
+	attempts, err := n.readLog()
+	n.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delivery log: %w", err)
+	}
+
+	var result []Attempt
+	for i := len(attempts) - 1; i >= 0; i-- {
+		a := attempts[i]
+		if q.Namespace != "" && a.Namespace != q.Namespace {
+			continue
+		}
+		if q.Webhook != "" && a.Webhook != q.Webhook {
+			continue
+		}
+		if q.Result != "" && a.Result != q.Result {
+			continue
+		}
+		if !q.Since.IsZero() && a.Time.Before(q.Since) {
+			continue
+		}
+		result = append(result, a)
+		if q.Limit > 0 && len(result) >= q.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Pending 返回命名空间中尚未完成的投递
+func (n *Notifier) Pending(namespace string) []Delivery {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var pending []Delivery
+	for _, d := range n.pending {
+		if namespace == "" || d.Namespace == namespace {
+			copied := *d
+			copied.Payload = nil
+			pending = append(pending, copied)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+	return pending
+}
+
+// WebhookNames 返回订阅了命名空间的 webhook 名称
+func (n *Notifier) WebhookNames(namespace string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var names []string
+	for name, t := range n.targets {
+		if t.accepts(namespace) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
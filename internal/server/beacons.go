@@ -0,0 +1,218 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cerebellum/internal/memory"
+)
+
+// memoryErrorStatus 将记忆操作错误映射为 HTTP 状态码
+func memoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, memory.ErrBeaconNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, memory.ErrBeaconExists):
+		return http.StatusConflict
+	case errors.Is(err, memory.ErrInvalidBeaconName), errors.Is(err, memory.ErrInvalidBeaconRange),
+		errors.Is(err, memory.ErrInvalidCursor), errors.Is(err, memory.ErrInvalidMetric),
+		errors.Is(err, memory.ErrInvalidSeries), errors.Is(err, memory.ErrInvalidZoom),
+		errors.Is(err, memory.ErrInvalidEntry), errors.Is(err, memory.ErrReservedType):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// HandleSetBeacon POST /api/beacon - 设置记忆信标
+//
+// 同名信标再次设置时记录为新的出现，按名称引用时取最后一次。
+func (s *Server) HandleSetBeacon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name     string                 `json:"name"`
+		Metadata map[string]interface{} `json:"metadata,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := memory.ValidateBeaconName(req.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	if err := ns.memory.SetBeacon(req.Name, req.Metadata); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set beacon: %v", err), memoryErrorStatus(err))
+		return
+	}
+	beacon, err := ns.memory.GetBeacon(req.Name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read beacon: %v", err), memoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "beacon_set",
+		"name":        beacon.Name,
+		"time":        beacon.Timestamp,
+		"occurrences": beacon.Occurrences,
+	})
+}
+
+// HandleListBeacons GET /api/beacons - 列出所有信标
+func (s *Server) HandleListBeacons(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	beacons, err := ns.memory.ListBeacons()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list beacons: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace": ns.name,
+		"beacons":   beacons,
+		"count":     len(beacons),
+	})
+}
+
+// HandleBeacon /api/beacons/{name}[/action] - 管理单个信标
+//
+//	GET    /api/beacons/{name}              信标及其全部出现
+//	DELETE /api/beacons/{name}              删除信标（记忆本身保留）
+//	POST   /api/beacons/{name}/rename       重命名：{"name": "new-name"}
+//	GET    /api/beacons/{ref}/diff?to={ref} 两个信标之间的变化摘要，省略 to 时到现在
+//
+// ref 为信标名称（最后一次出现）或 "name@N"（第 N 次出现）。
+func (s *Server) HandleBeacon(w http.ResponseWriter, r *http.Request) {
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	name, action, _ := strings.Cut(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/beacons/"), "/"), "/")
+	if name == "" {
+		http.Error(w, "Beacon name required", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		beacon, err := ns.memory.GetBeacon(name)
+		if err != nil {
+			http.Error(w, err.Error(), memoryErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(beacon)
+
+	case action == "" && r.Method == http.MethodDelete:
+		if err := ns.memory.DeleteBeacon(name); err != nil {
+			http.Error(w, err.Error(), memoryErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "beacon_deleted",
+			"name":   name,
+		})
+
+	case action == "rename" && r.Method == http.MethodPost:
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := ns.memory.RenameBeacon(name, req.Name); err != nil {
+			http.Error(w, err.Error(), memoryErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "beacon_renamed",
+			"from":   name,
+			"name":   req.Name,
+		})
+
+	case action == "diff" && r.Method == http.MethodGet:
+		s.handleBeaconDiff(w, r, ns, name)
+
+	case action != "" && action != "rename" && action != "diff":
+		http.Error(w, "Not found", http.StatusNotFound)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBeaconDiff 汇总两个信标之间的记忆和任务执行情况
+func (s *Server) handleBeaconDiff(w http.ResponseWriter, r *http.Request, ns *namespace, from string) {
+	diff, err := ns.memory.DiffBeacons(from, r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), memoryErrorStatus(err))
+		return
+	}
+
+	runs, err := ns.planner.RunsBetween(diff.Since, diff.Until)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to collect runs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	byStatus := make(map[string]int)
+	byTask := make(map[string]map[string]int)
+	for _, run := range runs {
+		byStatus[run.Status]++
+		if byTask[run.TaskID] == nil {
+			byTask[run.TaskID] = make(map[string]int)
+		}
+		byTask[run.TaskID][run.Status]++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace": ns.name,
+		"diff":      diff,
+		"runs": map[string]interface{}{
+			"total":     len(runs),
+			"by_status": byStatus,
+			"by_task":   byTask,
+		},
+	})
+}
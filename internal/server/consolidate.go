@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cerebellum/internal/digest"
+	"cerebellum/internal/memory"
+)
+
+// consolidateTimeout 单个命名空间一次整合的最长时间
+const consolidateTimeout = 10 * time.Minute
+
+// StartConsolidator 按 consolidation.interval 整合各命名空间的旧记忆（阻塞），未配置时立即返回
+func (s *Server) StartConsolidator() {
+	if s.cfg.Consolidation.Interval == "" {
+		return
+	}
+	interval, _ := time.ParseDuration(s.cfg.Consolidation.Interval)
+	log.Printf("✓ Consolidating memory older than %s every %s", s.cfg.Consolidation.After, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, ns := range s.namespaceList() {
+			if ns.memory == nil {
+				continue
+			}
+			result, err := s.consolidate(context.Background(), ns, s.consolidateBefore(now), s.cfg.Consolidation.MaxSummaries)
+			if err != nil {
+				log.Printf("Warning: Failed to consolidate memory for namespace %s: %v", ns.name, err)
+				continue
+			}
+			if result.Hourly+result.Daily > 0 {
+				log.Printf("✓ Namespace %s: wrote %d hourly and %d daily summaries", ns.name, result.Hourly, result.Daily)
+			}
+		}
+	}
+}
+
+// consolidateBefore 返回可以整合的截止时间
+func (s *Server) consolidateBefore(now time.Time) time.Time {
+	after, _ := time.ParseDuration(s.cfg.Consolidation.After)
+	return now.Add(-after)
+}
+
+// consolidate 整合一个命名空间的记忆
+func (s *Server) consolidate(ctx context.Context, ns *namespace, before time.Time, maxSummaries int) (*memory.ConsolidateResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, consolidateTimeout)
+	defer cancel()
+	return ns.memory.Consolidate(ctx, memory.ConsolidateOptions{
+		Before:       before,
+		Types:        s.cfg.Consolidation.Types,
+		MaxSummaries: maxSummaries,
+		Summarize:    s.summarizeMemory,
+	})
+}
+
+// summarizeMemory 调用本地模型归纳一个时段的记忆
+func (s *Server) summarizeMemory(ctx context.Context, level string, start, end time.Time, entries []memory.MemoryEntry) (string, error) {
+	maxTokens := s.cfg.Consolidation.MaxTokens
+	result, err := s.llm.GenerateLimited(ctx, digest.SummaryPrompt(level, start, end, entries, maxTokens), maxTokens)
+	if err != nil {
+		return "", err
+	}
+	return result.Response, nil
+}
+
+// HandleConsolidateMemory POST /api/memory/consolidate - 立即整合旧记忆
+//
+// 参数（可选）：
+//   - before  只整合在此之前结束的时段（RFC3339 或 Unix 秒），默认现在减去 consolidation.after
+//   - max     本次最多生成的摘要数，默认 consolidation.max_summaries
+func (s *Server) HandleConsolidateMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	before, err := parseTimeParam(query.Get("before"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid before: %v", err), http.StatusBadRequest)
+		return
+	}
+	if before.IsZero() {
+		before = s.consolidateBefore(time.Now())
+	}
+	maxSummaries := s.cfg.Consolidation.MaxSummaries
+	if v := query.Get("max"); v != "" {
+		if maxSummaries, err = strconv.Atoi(v); err != nil || maxSummaries < 1 {
+			http.Error(w, "Invalid max", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := s.consolidate(r.Context(), ns, before, maxSummaries)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to consolidate memory: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "consolidated",
+		"namespace": ns.name,
+		"before":    before,
+		"result":    result,
+	})
+}
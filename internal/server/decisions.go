@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cerebellum/internal/task"
+)
+
+// AnswerDecisionRequest 大脑对决策请求的回答
+type AnswerDecisionRequest struct {
+	Answer  string           `json:"answer"`
+	Comment string           `json:"comment,omitempty"`
+	Tasks   []task.BrainTask `json:"tasks,omitempty"` // 随回答创建或更新的后续任务
+}
+
+// HandleAPIDecisions /api/decisions[/id] - 小脑向大脑提出的决策请求
+//
+//	GET  /api/decisions        列出决策请求（status=pending|answered|all，默认 pending）
+//	GET  /api/decisions/{id}   获取单个决策请求
+//	POST /api/decisions/{id}   回答：{"answer": "...", "comment": "...", "tasks": [...]}
+func (s *Server) HandleAPIDecisions(w http.ResponseWriter, r *http.Request) {
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/decisions"), "/")
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "":
+			status = task.DecisionPending
+		case "all":
+			status = ""
+		case task.DecisionPending, task.DecisionAnswered:
+		default:
+			http.Error(w, fmt.Sprintf("Invalid status: %s", status), http.StatusBadRequest)
+			return
+		}
+		decisions := ns.planner.ListDecisions(status)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"namespace": ns.name,
+			"decisions": decisions,
+			"count":     len(decisions),
+		})
+
+	case id != "" && r.Method == http.MethodGet:
+		decision, err := ns.planner.GetDecision(id)
+		if err != nil {
+			http.Error(w, err.Error(), taskErrorStatus(err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(decision)
+
+	case id != "" && r.Method == http.MethodPost:
+		s.handleAnswerDecision(w, r, ns, id)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAnswerDecision 回答决策请求，后续任务和回答由计划器一起记录
+func (s *Server) handleAnswerDecision(w http.ResponseWriter, r *http.Request, ns *namespace, id string) {
+	var req AnswerDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	for _, t := range req.Tasks {
+		if err := t.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid task %s: %v", t.ID, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	decision, result, err := ns.planner.AnswerDecision(id, req.Answer, req.Comment, req.Tasks)
+	if err != nil {
+		http.Error(w, err.Error(), taskErrorStatus(err))
+		return
+	}
+
+	// 后续任务未生效时不记录回答，大脑可以修正后重试
+	if decision == nil {
+		status, code := "conflict", http.StatusConflict
+		if result.Quota != nil {
+			status, code = "quota_exceeded", http.StatusTooManyRequests
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":        status,
+			"upsert_result": result,
+		})
+		return
+	}
+	s.triggerExecutor()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "answered",
+		"decision":      decision,
+		"upsert_result": result,
+	})
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cerebellum/internal/digest"
+)
+
+// HandleAPIDigest GET /api/digest - 由本地模型归纳的精简报告
+//
+// 参数：
+//   - beacon     从该信标最后一次设置的时间开始（name@N 为第 N 次）
+//   - since      起始时间（RFC3339 或 Unix 秒），未给出 beacon/since 时取 digest.window 之前
+//   - until      结束时间，默认现在
+//   - max_tokens 报告的 token 预算，默认 digest.max_tokens
+//   - format     json（默认）或 text（只返回【监控报告】全文）
+func (s *Server) HandleAPIDigest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	until, err := parseTimeParam(query.Get("until"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid until: %v", err), http.StatusBadRequest)
+		return
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid since: %v", err), http.StatusBadRequest)
+		return
+	}
+	if beacon := query.Get("beacon"); beacon != "" {
+		if ns.memory == nil {
+			http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+			return
+		}
+		if since, err = ns.memory.BeaconTime(beacon); err != nil {
+			http.Error(w, err.Error(), memoryErrorStatus(err))
+			return
+		}
+	}
+	if since.IsZero() {
+		window, _ := time.ParseDuration(s.cfg.Digest.Window)
+		since = until.Add(-window)
+	}
+
+	budget := s.cfg.Digest.MaxTokens
+	if v := query.Get("max_tokens"); v != "" {
+		if budget, err = strconv.Atoi(v); err != nil || budget < 1 {
+			http.Error(w, "Invalid max_tokens", http.StatusBadRequest)
+			return
+		}
+	}
+
+	in, err := s.digestInput(ns, since, until)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to collect runs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	report := digest.Build(r.Context(), s.generateDigest, in, budget)
+	if report.Source == digest.SourceLLM {
+		report.Model = s.llm.GetModel()
+	}
+
+	if query.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, report.Text)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// digestInput 收集时间范围内的执行记录与告警
+func (s *Server) digestInput(ns *namespace, since, until time.Time) (digest.Input, error) {
+	in := digest.Input{Namespace: ns.name, Since: since, Until: until}
+
+	runs, err := ns.planner.RunsBetween(since, until)
+	if err != nil {
+		return in, err
+	}
+	commands := make(map[string]string)
+	for _, plan := range ns.planner.GetAllPlans() {
+		commands[plan.ID] = plan.Command
+	}
+	for _, run := range runs {
+		in.Runs = append(in.Runs, digest.Run{
+			TaskID:     run.TaskID,
+			Command:    commands[run.TaskID],
+			Status:     run.Status,
+			StartedAt:  run.StartedAt,
+			DurationMs: run.DurationMs,
+			Result:     run.Result,
+			Error:      run.Error,
+		})
+	}
+
+	if ns.memory != nil {
+		alerts, err := ns.memory.ReadRange(since, until, "task_alert")
+		if err != nil {
+			return in, err
+		}
+		for _, entry := range alerts {
+			in.Alerts = append(in.Alerts, digest.Alert{TaskID: entry.TaskID, Time: entry.Timestamp, Content: entry.Content})
+		}
+	}
+	return in, nil
+}
+
+// generateDigest 调用本地模型生成摘要
+func (s *Server) generateDigest(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	result, err := s.llm.GenerateLimited(ctx, prompt, maxTokens)
+	if err != nil {
+		return "", err
+	}
+	return result.Response, nil
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cerebellum/internal/events"
+)
+
+// 长轮询与 SSE 参数
+const (
+	defaultPollTimeout = 30 * time.Second
+	maxPollTimeout     = 120 * time.Second
+	sseKeepAlive       = 15 * time.Second
+)
+
+// HandleAPIEvents GET /api/events - 本命名空间的事件流（长轮询或 SSE）
+//
+// 参数：
+//   - cursor  上次返回的游标；为空只接收之后的事件，0 从缓冲区中最早的事件开始
+//   - types   逗号分隔的事件类型：task_change,alert,decision,memory,beacon（默认全部）
+//   - limit   单次最多返回的事件数（默认 100）
+//   - timeout 长轮询没有事件时的最长等待时间（默认 30s，最长 120s）
+//
+// 请求头 Accept: text/event-stream 或参数 stream=sse 时以 SSE 持续推送，
+// 重连时 Last-Event-ID 作为游标。
+func (s *Server) HandleAPIEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	cursor := query.Get("cursor")
+	types := make(map[string]bool)
+	for _, t := range strings.Split(query.Get("types"), ",") {
+		switch t = strings.TrimSpace(t); t {
+		case "":
+		case events.TypeTaskChange, events.TypeAlert, events.TypeDecision, events.TypeMemory, events.TypeBeacon:
+			types[t] = true
+		default:
+			http.Error(w, fmt.Sprintf("Unknown event type: %s", t), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 100
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if query.Get("stream") == "sse" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		if id := r.Header.Get("Last-Event-ID"); id != "" {
+			cursor = id
+		}
+		s.streamEvents(w, r, ns, cursor, types, limit)
+		return
+	}
+
+	timeout := defaultPollTimeout
+	if v := query.Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "Invalid timeout", http.StatusBadRequest)
+			return
+		}
+		timeout = min(d, maxPollTimeout)
+	}
+
+	list, next, gap, err := ns.events.Read(cursor, types, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 没有新事件时等待，直到有匹配的事件或超时
+	deadline := time.Now().Add(timeout)
+	for len(list) == 0 {
+		remaining := time.Until(deadline)
+		if remaining <= 0 || !ns.events.Wait(r.Context(), next, remaining) {
+			break
+		}
+		var more bool
+		list, next, more, _ = ns.events.Read(next, types, limit)
+		gap = gap || more
+	}
+
+	if list == nil {
+		list = []events.Event{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace": ns.name,
+		"events":    list,
+		"count":     len(list),
+		"cursor":    next,
+		"gap":       gap,
+	})
+}
+
+// streamEvents 以 SSE 持续推送事件，直到客户端断开
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, ns *namespace, cursor string, types map[string]bool, limit int) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// 先校验游标，出错时还能返回普通错误响应
+	if _, _, _, err := ns.events.Read(cursor, nil, 1); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		list, next, gap, _ := ns.events.Read(cursor, types, limit)
+		if gap {
+			fmt.Fprintf(w, "event: gap\ndata: {\"cursor\":%q}\n\n", next)
+		}
+		for _, e := range list {
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.Cursor, e.Type, data)
+		}
+		cursor = next
+		flusher.Flush()
+
+		if !ns.events.Wait(r.Context(), cursor, sseKeepAlive) {
+			if r.Context().Err() != nil {
+				return
+			}
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
@@ -1,19 +1,22 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"cerebellum/internal/config"
 	"cerebellum/internal/llm"
-	"cerebellum/internal/memory"
+	"cerebellum/internal/notify"
 	"cerebellum/internal/store"
 	"cerebellum/internal/task"
 )
@@ -23,10 +26,11 @@ type Server struct {
 	cfg            *config.Config
 	store          *store.MarkdownStore
 	llm            *llm.OllamaClient
-	planner        *task.PlanGenerator
 	systemIdentity string
-	memory         *memory.JSONLMemory
-	mu             sync.Mutex
+	namespaces     map[string]*namespace
+	nsMu           sync.Mutex
+	notifier       *notify.Notifier
+	runNow         chan struct{}
 }
 
 // NewServer creates a new HTTP server
@@ -37,118 +41,144 @@ func NewServer(cfg *config.Config, store *store.MarkdownStore, llm *llm.OllamaCl
 		systemIdentity = string(content)
 	}
 
-	// Initialize memory system
-	mem, err := memory.NewJSONLMemory("./data")
-	if err != nil {
-		log.Printf("Warning: Failed to initialize memory: %v", err)
-		mem = nil
-	}
-
-	// Initialize planner with memory and data directory
-	planner := task.NewPlanGenerator(mem)
-	planner.SetDataDir("./data")
-
-	// Load previous tasks from disk
-	if err := planner.LoadTasks(); err != nil {
-		log.Printf("Info: No previous tasks to load: %v", err)
-	} else {
-		log.Println("✓ Previous tasks loaded from disk")
-		// Show resumable tasks
-		resumable := planner.GetResumableTasks()
-		if len(resumable) > 0 {
-			log.Printf("✓ Found %d tasks to resume", len(resumable))
-		}
-	}
-
-	return &Server{
+	s := &Server{
 		cfg:            cfg,
 		store:          store,
 		llm:            llm,
-		planner:        planner,
 		systemIdentity: systemIdentity,
-		memory:         mem,
+		namespaces:     make(map[string]*namespace),
+		notifier:       newNotifier(cfg),
+		runNow:         make(chan struct{}, 1),
 	}
+	// 打开所有已知命名空间并恢复各自的任务
+	for _, name := range discoverNamespaces(cfg) {
+		s.openNamespace(name)
+	}
+	return s
 }
 
 // StartTaskExecutor 启动任务执行器（带持久化和智能报告）
 func (s *Server) StartTaskExecutor() {
 	// 立即执行一次，恢复之前的任务
-	s.mu.Lock()
-	resumableTasks := s.planner.GetResumableTasks()
-	if len(resumableTasks) > 0 {
-		log.Printf("Resuming %d tasks from previous session", len(resumableTasks))
-		s.planner.ExecuteTasks(s.executeCommand)
-		// 保存执行后的状态
-		if err := s.planner.SaveTasks(); err != nil {
-			log.Printf("Warning: Failed to save tasks: %v", err)
+	for _, ns := range s.namespaceList() {
+		resumableTasks := ns.planner.GetResumableTasks()
+		if len(resumableTasks) > 0 {
+			log.Printf("Resuming %d tasks from previous session in namespace %s", len(resumableTasks), ns.name)
+			s.startNamespaceRun(ns)
 		}
 	}
-	s.mu.Unlock()
 
 	// 启动定时器
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		s.mu.Lock()
-
-		// 执行任务
-		s.planner.ExecuteTasks(s.executeCommand)
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.runNow:
+		}
 
-		// 保存任务状态到磁盘
-		if err := s.planner.SaveTasks(); err != nil {
-			log.Printf("Warning: Failed to save tasks: %v", err)
+		for _, ns := range s.namespaceList() {
+			s.startNamespaceRun(ns)
 		}
+	}
+}
 
-		// 检查是否有显著变化（变化数 > 1），如果有则触发报告
-		if s.planner.HasSignificantChanges() {
-			changes := s.planner.GetAndClearChanges()
-			log.Printf("Significant changes detected (%d), notifying brain...", len(changes))
-
-			// 将变化记录到内存
-			if s.memory != nil {
-				for _, change := range changes {
-					s.memory.Write("task_change", change.TaskID,
-						fmt.Sprintf("Task %s: %s -> %s", change.Type, change.OldStatus, change.NewStatus),
-						change)
-				}
-			}
+// startNamespaceRun 在后台执行一个命名空间，上一轮还没结束时跳过本轮
+//
+// 各命名空间互不等待，一个大脑卡住的任务只会推迟它自己的下一轮；
+// PlanGenerator 自己加锁，执行期间 API 照常读写任务。
+func (s *Server) startNamespaceRun(ns *namespace) {
+	if !ns.running.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer ns.running.Store(false)
+		s.runNamespace(ns)
+	}()
+}
 
-			// 触发大脑报告（这里可以添加HTTP回调或Webhook）
-			s.notifyBrain(changes)
+// runNamespace 执行命名空间中到期的任务，保存状态并上报显著变化
+func (s *Server) runNamespace(ns *namespace) {
+	// 执行任务
+	ns.planner.ExecuteTasks(s.executeCommand)
+
+	// 保存任务状态到磁盘
+	if err := ns.planner.SaveTasks(); err != nil {
+		log.Printf("Warning: Failed to save tasks for namespace %s: %v", ns.name, err)
+	}
+
+	// 检查是否有显著变化（变化数 > 1），如果有则触发报告
+	if ns.planner.HasSignificantChanges() {
+		changes := ns.planner.GetAndClearChanges()
+		log.Printf("Significant changes detected in namespace %s (%d), notifying brain...", ns.name, len(changes))
+
+		// 将变化记录到内存
+		if ns.memory != nil {
+			for _, change := range changes {
+				ns.memory.Write("task_change", change.TaskID,
+					fmt.Sprintf("Task %s: %s -> %s", change.Type, change.OldStatus, change.NewStatus),
+					change)
+			}
 		}
 
-		s.mu.Unlock()
+		// 通过 webhook 向大脑报告
+		s.notifyBrain(ns, changes)
 	}
 }
 
-// notifyBrain 通知大脑有重要变化
-func (s *Server) notifyBrain(changes []task.TaskChange) {
-	// 记录到日志（实际可以实现HTTP回调通知大脑）
-	log.Printf("[BRAIN NOTIFICATION] %d task changes reported", len(changes))
+// notifyBrain 通知大脑有重要变化：写入 webhook outbox，由通知器异步投递
+func (s *Server) notifyBrain(ns *namespace, changes []task.TaskChange) {
+	log.Printf("[BRAIN NOTIFICATION] %s: %d task changes reported", ns.name, len(changes))
+	if s.notifier == nil {
+		return
+	}
+
+	report := map[string]interface{}{
+		"timestamp": time.Now().Format(time.RFC3339),
+		"type":      "task_changes",
+		"namespace": ns.name,
+		"count":     len(changes),
+		"changes":   changes,
+		"summary":   ns.planner.GetReport(),
+	}
+	if err := s.notifier.Enqueue(ns.name, "task_changes", report); err != nil {
+		log.Printf("Warning: Failed to queue webhook notification: %v", err)
+	}
+}
 
-	// 这里可以添加：发送到大脑的HTTP端点
-	// report := map[string]interface{}{
-	//     "timestamp": time.Now().Format(time.RFC3339),
-	//     "type":      "task_changes",
-	//     "count":     len(changes),
-	//     "changes":   changes,
-	//     "summary":   s.planner.GetReport(),
-	// }
-	// go s.sendToBrain(report)
+// triggerExecutor 唤醒任务执行器，不等待下一个 30 秒周期
+func (s *Server) triggerExecutor() {
+	select {
+	case s.runNow <- struct{}{}:
+	default:
+	}
 }
 
 // executeCommand 执行命令
-func (s *Server) executeCommand(command string) (string, error) {
+func (s *Server) executeCommand(ctx context.Context, command string) (task.ExecResult, error) {
 	prompt := fmt.Sprintf(`<system_instructions>
 You are Cerebellum task executor. Execute the following command and provide the result.
 
 Command: %s
 
 Please execute this command and return the result.
+If you cannot continue without a decision from the brain, end the result with:
+【需要决策】
+问题：<question>
+选项：<option 1> / <option 2>
 </system_instructions>`, command)
 
-	return s.llm.Generate(prompt)
+	result, err := s.llm.GenerateWithUsage(ctx, prompt)
+	if err != nil {
+		return task.ExecResult{}, err
+	}
+	return task.ExecResult{
+		Output:           result.Response,
+		Model:            result.Model,
+		PromptTokens:     result.PromptTokens,
+		CompletionTokens: result.CompletionTokens,
+	}, nil
 }
 
 // === Handler Functions ===
@@ -175,6 +205,8 @@ type HandleTasksResponse struct {
 	Status    string `json:"status"`
 	TaskCount int    `json:"task_count"`
 	Message   string `json:"message,omitempty"`
+
+	*task.UpsertResult
 }
 
 // HandleAPITasks POST /api/tasks - 大脑分配任务给小脑
@@ -190,16 +222,61 @@ func (s *Server) HandleAPITasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	s.planner.GeneratePlan(req.Tasks)
-	planCount := len(s.planner.GetAllPlans())
-	s.mu.Unlock()
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	for _, t := range req.Tasks {
+		if err := t.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid task %s: %v", t.ID, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	result := ns.planner.GeneratePlan(req.Tasks)
+	planCount := len(ns.planner.GetAllPlans())
 
 	w.Header().Set("Content-Type", "application/json")
+	if result.Quota != nil {
+		// 超出命名空间任务数上限时整批任务都未生效
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(HandleTasksResponse{
+			Status:       "quota_exceeded",
+			TaskCount:    planCount,
+			Message:      fmt.Sprintf("namespace %s allows %d tasks, no changes applied", ns.name, result.Quota.Limit),
+			UpsertResult: result,
+		})
+		return
+	}
+	if len(result.Conflicts) > 0 {
+		// 有版本冲突时整批任务都未生效
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(HandleTasksResponse{
+			Status:       "conflict",
+			TaskCount:    planCount,
+			Message:      fmt.Sprintf("%d tasks failed if_match, no changes applied", len(result.Conflicts)),
+			UpsertResult: result,
+		})
+		return
+	}
+	if len(result.Running) > 0 {
+		// 正在执行的任务不能改变类型，整批任务都未生效
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(HandleTasksResponse{
+			Status:       "conflict",
+			TaskCount:    planCount,
+			Message:      fmt.Sprintf("%d running tasks cannot change type, no changes applied", len(result.Running)),
+			UpsertResult: result,
+		})
+		return
+	}
+
 	json.NewEncoder(w).Encode(HandleTasksResponse{
-		Status:    "accepted",
-		TaskCount: planCount,
-		Message:   fmt.Sprintf("Received %d tasks from brain", len(req.Tasks)),
+		Status:       "accepted",
+		TaskCount:    planCount,
+		Message:      fmt.Sprintf("Received %d tasks from brain", len(req.Tasks)),
+		UpsertResult: result,
 	})
 }
 
@@ -210,13 +287,17 @@ func (s *Server) HandleAPIReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	report := s.planner.GetReport()
-	plans := s.planner.GetAllPlans()
-	s.mu.Unlock()
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	report := ns.planner.GetReport()
+	plans := ns.planner.GetAllPlans()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace":       ns.name,
 		"report":          report,
 		"total_plans":     len(plans),
 		"pending_count":   len(report["pending"].([]string)),
@@ -232,53 +313,237 @@ func (s *Server) HandleAPIStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.Lock()
-	report := s.planner.GetReport()
-	plans := s.planner.GetAllPlans()
-	s.mu.Unlock()
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	report := ns.planner.GetReport()
+	plans := ns.planner.GetAllPlans()
+	running := ns.planner.GetRunningTasks()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":          "running",
+		"namespace":       ns.name,
 		"llm_host":        s.llm.GetHost(),
 		"llm_model":       s.llm.GetModel(),
 		"total_tasks":     len(plans),
 		"pending_tasks":   report["pending_count"],
 		"completed_tasks": report["completed_count"],
 		"failed_tasks":    report["failed_count"],
+		"running_tasks":   running,
+		"recovery":        ns.planner.GetRecoveryReport(),
 		"last_updated":    time.Now().Format(time.RFC3339),
 	})
 }
 
-// HandleAPITaskDelete DELETE /api/task/{id} - 删除已完成任务
-func (s *Server) HandleAPITaskDelete(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
+// HandleAPITask /api/task/{id}[/action] - 单个任务的查询与控制
+//
+//	GET    /api/task/{id}        获取完整任务计划
+//	PATCH  /api/task/{id}        修改 command、interval、conditions、metrics、expires_at、max_runs、
+//	                             retention、priority、owner；if_match 做版本检查，
+//	                             已结束的一次性任务换了命令会重新执行
+//	DELETE /api/task/{id}        删除任务（周期任务或一次性任务）
+//	POST   /api/task/{id}/pause  暂停周期任务
+//	POST   /api/task/{id}/resume 恢复周期任务
+//	POST   /api/task/{id}/cancel 取消正在执行或未执行的任务
+//	POST   /api/task/{id}/run    立即执行
+//	GET    /api/task/{id}/runs   执行历史（limit/offset/since/until）
+func (s *Server) HandleAPITask(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/task/"), "/")
+	if id == "" {
+		http.Error(w, "Task ID required", http.StatusBadRequest)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+
+	if action == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.handleGetTask(w, ns, id)
+		case http.MethodPatch:
+			s.handleUpdateTask(w, r, ns, id)
+		case http.MethodDelete:
+			s.handleDeleteTask(w, ns, id)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if action == "runs" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleListRuns(w, r, ns, id)
+		return
+	}
+
+	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	id := strings.TrimPrefix(r.URL.Path, "/api/task/")
-	if id == "" {
-		http.Error(w, "Task ID required", http.StatusBadRequest)
+	var err error
+	var status string
+	switch action {
+	case "pause":
+		err, status = ns.planner.PauseTask(id), "paused"
+	case "resume":
+		err, status = ns.planner.ResumeTask(id), "resumed"
+	case "cancel":
+		err, status = ns.planner.CancelTask(id), "cancelled"
+	case "run":
+		err, status = ns.planner.TriggerTask(id), "triggered"
+		if err == nil {
+			s.triggerExecutor()
+		}
+	default:
+		http.Error(w, fmt.Sprintf("Unknown task action: %s", action), http.StatusNotFound)
 		return
 	}
 
-	s.mu.Lock()
-	removed := s.planner.RemoveCompletedTask(id)
-	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), taskErrorStatus(err))
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if removed {
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "deleted",
-			"id":     id,
-		})
-	} else {
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "not_found",
-			"id":      id,
-			"message": "Task not found or not completed",
-		})
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": status,
+		"id":     id,
+	})
+}
+
+// handleGetTask GET /api/task/{id}
+func (s *Server) handleGetTask(w http.ResponseWriter, ns *namespace, id string) {
+	plan, err := ns.planner.GetTask(id)
+	if err != nil {
+		http.Error(w, err.Error(), taskErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, plan.Version))
+	json.NewEncoder(w).Encode(plan)
+}
+
+// handleUpdateTask PATCH /api/task/{id}
+func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request, ns *namespace, id string) {
+	var patch task.TaskPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// If-Match 头与请求体中的 if_match 等价
+	if header := strings.Trim(r.Header.Get("If-Match"), `"`); header != "" && patch.IfMatch == nil {
+		version, err := strconv.Atoi(header)
+		if err != nil {
+			http.Error(w, "Invalid If-Match header", http.StatusBadRequest)
+			return
+		}
+		patch.IfMatch = &version
+	}
+
+	plan, err := ns.planner.UpdateTask(id, patch)
+	if err != nil {
+		http.Error(w, err.Error(), taskErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// handleDeleteTask DELETE /api/task/{id}
+func (s *Server) handleDeleteTask(w http.ResponseWriter, ns *namespace, id string) {
+	if err := ns.planner.RemoveTask(id); err != nil {
+		http.Error(w, err.Error(), taskErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "deleted",
+		"id":     id,
+	})
+}
+
+// handleListRuns GET /api/task/{id}/runs - 分页查询执行历史
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request, ns *namespace, id string) {
+	query := r.URL.Query()
+	q := task.RunQuery{Limit: 50}
+
+	var err error
+	if q.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid since: %v", err), http.StatusBadRequest)
+		return
+	}
+	if q.Until, err = parseTimeParam(query.Get("until")); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid until: %v", err), http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil || q.Offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	runs, total, err := ns.planner.ListRuns(id, q)
+	if err != nil {
+		http.Error(w, err.Error(), taskErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"task_id": id,
+		"runs":    runs,
+		"count":   len(runs),
+		"total":   total,
+		"offset":  q.Offset,
+		"limit":   q.Limit,
+	})
+}
+
+// parseTimeParam 解析查询参数中的时间（RFC3339 或 Unix 秒），空字符串返回零值
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// taskErrorStatus 将任务操作错误映射为 HTTP 状态码
+func taskErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, task.ErrTaskNotFound), errors.Is(err, task.ErrDecisionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, task.ErrTaskRunning):
+		return http.StatusConflict
+	case errors.Is(err, task.ErrInvalidOperation):
+		return http.StatusBadRequest
+	case errors.Is(err, task.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
 	}
 }
 
@@ -473,116 +738,11 @@ func (s *Server) HandleReload(w http.ResponseWriter, r *http.Request) {
 
 // SaveTasks 保存任务状态到磁盘（用于优雅关闭）
 func (s *Server) SaveTasks() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.planner.SaveTasks()
-}
-
-// HandleSetBeacon POST /api/beacon - 设置记忆信标
-func (s *Server) HandleSetBeacon(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req struct {
-		Name     string                 `json:"name"`
-		Metadata map[string]interface{} `json:"metadata,omitempty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	if req.Name == "" {
-		http.Error(w, "Beacon name required", http.StatusBadRequest)
-		return
-	}
-
-	if s.memory == nil {
-		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
-		return
-	}
-
-	if err := s.memory.SetBeacon(req.Name, req.Metadata); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to set beacon: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status": "beacon_set",
-		"name":   req.Name,
-		"time":   time.Now().Format(time.RFC3339),
-	})
-}
-
-// HandleReadMemory GET /api/memory?beacon=xxx&type=xxx - 读取记忆
-func (s *Server) HandleReadMemory(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	if s.memory == nil {
-		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
-		return
-	}
-
-	beacon := r.URL.Query().Get("beacon")
-	entryType := r.URL.Query().Get("type")
-
-	if beacon == "" {
-		// 如果没有指定信标，返回最近的100条
-		entries, err := s.memory.ReadRecent(100)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to read memory: %v", err), http.StatusInternalServerError)
-			return
+	var errs []error
+	for _, ns := range s.namespaceList() {
+		if err := ns.planner.SaveTasks(); err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", ns.name, err))
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"entries": entries,
-			"count":   len(entries),
-		})
-		return
 	}
-
-	// 从信标读取
-	entries, err := s.memory.ReadSinceBeacon(beacon, entryType)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read memory: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"beacon":  beacon,
-		"entries": entries,
-		"count":   len(entries),
-	})
-}
-
-// HandleListBeacons GET /api/beacons - 列出所有信标
-func (s *Server) HandleListBeacons(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	if s.memory == nil {
-		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
-		return
-	}
-
-	beacons, err := s.memory.ListBeacons()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to list beacons: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"beacons": beacons,
-		"count":   len(beacons),
-	})
+	return errors.Join(errs...)
 }
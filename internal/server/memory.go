@@ -0,0 +1,360 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cerebellum/internal/memory"
+)
+
+// HandleMemory /api/memory - GET 检索记忆，POST 写入大脑的记忆
+func (s *Server) HandleMemory(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.HandleReadMemory(w, r)
+	case http.MethodPost:
+		s.HandleWriteMemory(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// maxWriteBytes 单次写入请求体的上限
+const maxWriteBytes = 16 << 20
+
+// HandleWriteMemory POST /api/memory - 大脑写入自己的决策、笔记和观察
+//
+// 请求体为单条 {"type","task_id","content","data"} 或批量 {"entries": [...]}（最多 1000 条）。
+// 类型为小写字母、数字和下划线，task_、decision_、beacon、summary_ 开头的类型由小脑保留；
+// 任何一条无效时整批都不写入，错误信息指出是第几条。
+func (s *Server) HandleWriteMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		memory.NewEntry
+		Entries []memory.NewEntry `json:"entries"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWriteBytes)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	entries := req.Entries
+	if req.Type != "" || req.Content != "" || len(req.Data) > 0 {
+		if len(entries) > 0 {
+			http.Error(w, "Use either a single entry or entries, not both", http.StatusBadRequest)
+			return
+		}
+		entries = []memory.NewEntry{req.NewEntry}
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	written, err := ns.memory.WriteBatch(entries)
+	if err != nil && len(written) == 0 {
+		http.Error(w, fmt.Sprintf("Failed to write memory: %v", err), memoryErrorStatus(err))
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: Wrote %d of %d memory entries for namespace %s: %v", len(written), len(entries), ns.name, err)
+	}
+
+	resp := map[string]interface{}{
+		"status":  "written",
+		"count":   len(written),
+		"entries": written,
+	}
+	status := http.StatusCreated
+	if err != nil {
+		resp["status"] = "partial"
+		resp["error"] = err.Error()
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleReadMemory GET /api/memory - 检索记忆
+//
+// 参数（均可选，可组合）：
+//   - since, until      时间范围（RFC3339 或 Unix 秒），until 不包含
+//   - beacon / start    从信标开始；end 到信标为止（name 为最后一次出现，name@N 为第 N 次）
+//   - type              条目类型，可重复或逗号分隔
+//   - task_id           任务 ID
+//   - q                 内容包含该子串（不区分大小写）
+//   - regex             内容匹配正则（RE2 语法）
+//   - limit             每页条数，默认 100，最大 1000
+//   - order             asc 或 desc，默认 asc；没有起点（since/beacon）时默认返回最新的一页，
+//     页内从旧到新，next_cursor 指向更早的一页
+//   - cursor            上一页返回的 next_cursor，其余参数需与上一页相同
+//   - zoom              hour 或 day：返回该层级的整合摘要而不是原始记忆（type 被忽略，task_id
+//     取包含该任务的摘要）；响应中的 consolidated_until 之后的时段尚未整合，需按原始记忆查询
+func (s *Server) HandleReadMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	search, err := parseMemorySearch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := ns.memory.Search(search)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read memory: %v", err), memoryErrorStatus(err))
+		return
+	}
+
+	order := "asc"
+	if search.Desc {
+		order = "desc"
+	}
+	resp := map[string]interface{}{
+		"namespace":   ns.name,
+		"entries":     page.Entries,
+		"count":       len(page.Entries),
+		"order":       order,
+		"has_more":    page.NextCursor != "",
+		"next_cursor": page.NextCursor,
+	}
+	if !page.Since.IsZero() {
+		resp["since"] = page.Since
+	}
+	if !page.Until.IsZero() {
+		resp["until"] = page.Until
+	}
+	if search.StartBeacon != "" {
+		resp["beacon"] = search.StartBeacon
+	}
+	if search.Zoom != "" {
+		until, err := ns.memory.ConsolidatedUntil(search.Zoom)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read memory: %v", err), memoryErrorStatus(err))
+			return
+		}
+		resp["zoom"] = search.Zoom
+		resp["consolidated_until"] = until
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseMemorySearch 解析 /api/memory 的查询参数
+func parseMemorySearch(r *http.Request) (memory.Search, error) {
+	query := r.URL.Query()
+	var search memory.Search
+	var err error
+
+	if search.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		return search, fmt.Errorf("Invalid since: %v", err)
+	}
+	if search.Until, err = parseTimeParam(query.Get("until")); err != nil {
+		return search, fmt.Errorf("Invalid until: %v", err)
+	}
+	search.StartBeacon = query.Get("beacon")
+	if search.StartBeacon == "" {
+		search.StartBeacon = query.Get("start")
+	}
+	search.EndBeacon = query.Get("end")
+	search.TaskID = query.Get("task_id")
+	search.Contains = query.Get("q")
+	search.Cursor = query.Get("cursor")
+
+	for _, v := range query["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				search.Types = append(search.Types, t)
+			}
+		}
+	}
+
+	if v := query.Get("regex"); v != "" {
+		if search.Pattern, err = regexp.Compile(v); err != nil {
+			return search, fmt.Errorf("Invalid regex: %v", err)
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		if search.Limit, err = strconv.Atoi(v); err != nil || search.Limit < 1 || search.Limit > memory.MaxSearchLimit {
+			return search, fmt.Errorf("Invalid limit (1-%d)", memory.MaxSearchLimit)
+		}
+	}
+
+	switch zoom := query.Get("zoom"); zoom {
+	case "", "raw":
+	case memory.LevelHour, memory.LevelDay:
+		search.Zoom = zoom
+	default:
+		return search, fmt.Errorf("Invalid zoom: %s (want raw, hour or day)", zoom)
+	}
+
+	switch order := query.Get("order"); order {
+	case "":
+		search.Latest = search.Since.IsZero() && search.StartBeacon == ""
+	case "asc":
+	case "desc":
+		search.Desc = true
+	default:
+		return search, fmt.Errorf("Invalid order: %s", order)
+	}
+
+	if !search.Since.IsZero() && !search.Until.IsZero() && !search.Since.Before(search.Until) {
+		return search, fmt.Errorf("since must be before until")
+	}
+	return search, nil
+}
+
+// maxImportBytes 单次导入请求体的上限
+const maxImportBytes = 256 << 20
+
+// exportContentTypes 导出格式对应的 Content-Type
+var exportContentTypes = map[string]string{
+	memory.FormatJSONL:   "application/x-ndjson",
+	memory.FormatCSV:     "text/csv; charset=utf-8",
+	memory.FormatParquet: "application/vnd.apache.parquet",
+}
+
+// exportWriter 记录是否已经开始输出，之后的错误只能记录日志
+type exportWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	e.written = true
+	return e.w.Write(p)
+}
+
+// HandleExportMemory GET /api/memory/export?format=jsonl|csv|parquet - 导出记忆
+//
+// 过滤参数与 /api/memory 相同（limit、cursor、order 除外），按时间正序流式导出全部匹配的条目。
+// CSV 将 data 中的字段展开为 data.<路径> 列；Parquet 的 data 列保留 JSON。
+func (s *Server) HandleExportMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = memory.FormatJSONL
+	}
+	if !memory.ValidExportFormat(format) {
+		http.Error(w, fmt.Sprintf("Invalid format: %s (want jsonl, csv or parquet)", format), http.StatusBadRequest)
+		return
+	}
+	search, err := parseMemorySearch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("memory-%s-%s.%s", ns.name, time.Now().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	out := &exportWriter{w: w}
+	count, err := ns.memory.Export(out, format, search)
+	if err != nil {
+		if !out.written {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, fmt.Sprintf("Failed to export memory: %v", err), memoryErrorStatus(err))
+			return
+		}
+		log.Printf("Warning: Memory export for namespace %s stopped after %d entries: %v", ns.name, count, err)
+	}
+}
+
+// HandleImportMemory POST /api/memory/import - 导入另一个实例导出的 JSONL 记忆
+//
+// 请求体为 JSONL（可用 Content-Encoding: gzip 压缩），完全相同的条目只保留一份。
+func (s *Server) HandleImportMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+	if format := r.URL.Query().Get("format"); format != "" && format != memory.FormatJSONL {
+		http.Error(w, fmt.Sprintf("Invalid format: %s (only jsonl can be imported)", format), http.StatusBadRequest)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid gzip body: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	result, err := ns.memory.Import(body)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace":  ns.name,
+		"status":     "imported",
+		"total":      result.Total,
+		"imported":   result.Imported,
+		"duplicates": result.Duplicates,
+		"invalid":    result.Invalid,
+	})
+}
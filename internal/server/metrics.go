@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cerebellum/internal/memory"
+)
+
+// HandleListMetrics GET /api/metrics - 列出指标序列及其最新值
+//
+// 参数与 /api/memory 相同的 since、until、beacon/start、end 和 task_id（均可选）。
+func (s *Server) HandleListMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	search, err := parseMemorySearch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metrics, err := ns.memory.ListMetrics(search)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list metrics: %v", err), memoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace": ns.name,
+		"metrics":   metrics,
+		"count":     len(metrics),
+	})
+}
+
+// HandleMetricSeries GET /api/metrics/series - 指标的降采样序列
+//
+// 参数：
+//   - name              指标名称（必填）
+//   - since, until      时间范围（RFC3339 或 Unix 秒）；省略时从第一个样本到现在
+//   - beacon / start    从信标开始；end 到信标为止
+//   - task_id           只统计该任务写入的样本
+//   - label             标签过滤 key:value，可重复
+//   - step              分桶宽度，如 "5m"；省略时按 buckets 均分
+//   - buckets           分桶数，默认 60，最大 10000
+func (s *Server) HandleMetricSeries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+
+	q, err := parseSeriesQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := ns.memory.MetricSeries(q)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to query series: %v", err), memoryErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace": ns.name,
+		"name":      result.Name,
+		"since":     result.Since,
+		"until":     result.Until,
+		"step":      result.Step,
+		"series":    result.Series,
+	})
+}
+
+// parseSeriesQuery 解析 /api/metrics/series 的查询参数
+func parseSeriesQuery(r *http.Request) (memory.SeriesQuery, error) {
+	query := r.URL.Query()
+	q := memory.SeriesQuery{
+		Name:        query.Get("name"),
+		TaskID:      query.Get("task_id"),
+		StartBeacon: query.Get("beacon"),
+		EndBeacon:   query.Get("end"),
+	}
+	if q.Name == "" {
+		return q, fmt.Errorf("name is required")
+	}
+	if q.StartBeacon == "" {
+		q.StartBeacon = query.Get("start")
+	}
+
+	var err error
+	if q.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		return q, fmt.Errorf("Invalid since: %v", err)
+	}
+	if q.Until, err = parseTimeParam(query.Get("until")); err != nil {
+		return q, fmt.Errorf("Invalid until: %v", err)
+	}
+
+	for _, v := range query["label"] {
+		key, value, ok := strings.Cut(v, ":")
+		if !ok || key == "" {
+			return q, fmt.Errorf("Invalid label %q (want key:value)", v)
+		}
+		if q.Labels == nil {
+			q.Labels = make(map[string]string)
+		}
+		q.Labels[key] = value
+	}
+
+	if v := query.Get("step"); v != "" {
+		if q.Step, err = time.ParseDuration(v); err != nil || q.Step < time.Second {
+			return q, fmt.Errorf("Invalid step (at least 1s)")
+		}
+	}
+	if v := query.Get("buckets"); v != "" {
+		if q.Buckets, err = strconv.Atoi(v); err != nil || q.Buckets < 1 || q.Buckets > memory.MaxSeriesBuckets {
+			return q, fmt.Errorf("Invalid buckets (1-%d)", memory.MaxSeriesBuckets)
+		}
+	}
+	return q, nil
+}
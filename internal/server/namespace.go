@@ -0,0 +1,267 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"cerebellum/internal/config"
+	"cerebellum/internal/events"
+	"cerebellum/internal/llm"
+	"cerebellum/internal/memory"
+	"cerebellum/internal/sqlstore"
+	"cerebellum/internal/task"
+)
+
+// namespaceHeader 选择命名空间的请求头
+const namespaceHeader = "X-Cerebellum-Namespace"
+
+// eventBufferSize 每个命名空间保留的最近事件数
+const eventBufferSize = 10000
+
+// namespace 一个大脑的隔离空间：独立的任务计划、记忆和信标
+type namespace struct {
+	name    string
+	planner *task.PlanGenerator
+	memory  *memory.JSONLMemory
+	events  *events.Bus
+
+	running atomic.Bool // 执行器正在执行该命名空间
+}
+
+// newNamespace 打开命名空间的存储，并按配置创建计划生成器
+func newNamespace(cfg *config.Config, llm *llm.OllamaClient, name string) *namespace {
+	// Initialize memory system and task store
+	mem, taskStore := openStorage(cfg.Storage, name)
+
+	// Initialize planner with memory and task store
+	planner := task.NewPlanGenerator(mem)
+	if taskStore != nil {
+		planner.SetStore(taskStore)
+	}
+	planner.SetJudge(llm.GenerateContext)
+	if retention, err := time.ParseDuration(cfg.Tasks.CompletedRetention); err == nil {
+		planner.SetRetention(retention)
+	}
+	if ttl, err := time.ParseDuration(cfg.Tasks.LeaseTTL); err == nil {
+		planner.SetLeaseTTL(ttl)
+	}
+	if timeout, err := time.ParseDuration(cfg.Tasks.RunTimeout); err == nil {
+		planner.SetRunTimeout(timeout)
+	}
+
+	// 命名空间配额
+	quota, _ := cfg.Namespace(name)
+	maxConcurrency := cfg.Tasks.MaxConcurrency
+	if quota.MaxConcurrency > 0 && quota.MaxConcurrency < maxConcurrency {
+		maxConcurrency = quota.MaxConcurrency
+	}
+	planner.SetSchedulerLimits(task.SchedulerLimits{
+		MaxConcurrency:   maxConcurrency,
+		OwnerConcurrency: cfg.Tasks.OwnerConcurrency,
+		OwnerLimits:      cfg.Tasks.OwnerLimits,
+	})
+	planner.SetMaxTasks(quota.MaxTasks)
+
+	// Load previous tasks from disk
+	if err := planner.LoadTasks(); err != nil {
+		log.Printf("Info: No previous tasks to load for namespace %s: %v", name, err)
+	} else {
+		log.Printf("✓ Previous tasks loaded for namespace %s", name)
+		// Show resumable tasks
+		resumable := planner.GetResumableTasks()
+		if len(resumable) > 0 {
+			log.Printf("✓ Found %d tasks to resume in namespace %s", len(resumable), name)
+		}
+	}
+
+	ns := &namespace{name: name, planner: planner, memory: mem, events: events.NewBus(eventBufferSize)}
+	ns.publishEvents()
+	return ns
+}
+
+// publishEvents 将任务变化和记忆写入转发到命名空间的事件流
+func (ns *namespace) publishEvents() {
+	ns.planner.SetChangeListener(func(change task.TaskChange) {
+		eventType := events.TypeTaskChange
+		switch change.Type {
+		case task.ChangeTypeAlert:
+			eventType = events.TypeAlert
+		case task.ChangeTypeDecision:
+			eventType = events.TypeDecision
+		}
+		ns.events.Publish(eventType, change.TaskID, change)
+	})
+	if ns.memory == nil {
+		return
+	}
+	ns.memory.SetListener(func(entry memory.MemoryEntry) {
+		eventType := events.TypeMemory
+		if memory.IsBeaconType(entry.Type) {
+			eventType = events.TypeBeacon
+		}
+		ns.events.Publish(eventType, entry.TaskID, entry)
+	})
+}
+
+// openStorage 根据配置创建命名空间的记忆与任务存储，失败时对应返回 nil
+func openStorage(cfg config.StorageConfig, name string) (*memory.JSONLMemory, task.Store) {
+	if cfg.Backend == "sqlite" {
+		path := cfg.NamespaceSQLitePath(name)
+		db, err := sqlstore.Open(path)
+		if err != nil {
+			log.Printf("Warning: Failed to open sqlite storage: %v", err)
+			return nil, nil
+		}
+		log.Printf("✓ Using sqlite storage at %s", path)
+		return memory.NewWithBackend(db.Memory()), db.Tasks()
+	}
+
+	dataDir := cfg.NamespaceDataDir(name)
+	mem, err := memory.NewJSONLMemory(dataDir)
+	if err != nil {
+		log.Printf("Warning: Failed to initialize memory: %v", err)
+		mem = nil
+	} else {
+		maxAge, _ := time.ParseDuration(cfg.MemoryMaxAge)
+		mem.SetRetention(int64(cfg.MemoryMaxTotalMB)<<20, maxAge)
+		mem.SetCompression(cfg.MemoryCompression == "gzip")
+	}
+	taskStore, err := task.NewFileStore(dataDir)
+	if err != nil {
+		log.Printf("Warning: Failed to initialize task store: %v", err)
+		return mem, nil
+	}
+	return mem, taskStore
+}
+
+// discoverNamespaces 返回默认、配置中列出和存储中已存在的命名空间
+func discoverNamespaces(cfg *config.Config) []string {
+	seen := map[string]bool{config.DefaultNamespace: true}
+	for _, ns := range cfg.Namespaces {
+		seen[ns.Name] = true
+	}
+
+	dir := filepath.Join(cfg.Storage.DataDir, "namespaces")
+	suffix := ""
+	if cfg.Storage.Backend == "sqlite" {
+		dir = filepath.Join(filepath.Dir(cfg.Storage.SQLitePath), "namespaces")
+		suffix = ".db"
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() != (suffix == "") || !strings.HasSuffix(entry.Name(), suffix) {
+				continue
+			}
+			if name := strings.TrimSuffix(entry.Name(), suffix); config.ValidNamespace(name) {
+				seen[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupNamespace 返回已打开的命名空间
+func (s *Server) lookupNamespace(name string) (*namespace, bool) {
+	s.nsMu.Lock()
+	defer s.nsMu.Unlock()
+	ns, ok := s.namespaces[name]
+	return ns, ok
+}
+
+// openNamespace 返回命名空间，首次使用时创建
+func (s *Server) openNamespace(name string) *namespace {
+	s.nsMu.Lock()
+	defer s.nsMu.Unlock()
+
+	if ns, ok := s.namespaces[name]; ok {
+		return ns
+	}
+	ns := newNamespace(s.cfg, s.llm, name)
+	s.namespaces[name] = ns
+	return ns
+}
+
+// namespaceList 按名称顺序返回已打开的命名空间
+func (s *Server) namespaceList() []*namespace {
+	s.nsMu.Lock()
+	defer s.nsMu.Unlock()
+
+	list := make([]*namespace, 0, len(s.namespaces))
+	for _, ns := range s.namespaces {
+		list = append(list, ns)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// requestAPIKey 从 X-API-Key 或 Authorization: Bearer 中读取 API key
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return ""
+}
+
+// resolveNamespace 根据 API key 或请求头确定命名空间，失败时写入错误响应
+//
+// 带 API key 的请求属于该 key 对应的命名空间；只带请求头的请求不能进入设置了
+// api_key 的命名空间，避免一个大脑冒用另一个大脑的任务和记忆。
+// 只接受启动时已打开（默认、配置中列出或存储中已存在）的命名空间，
+// 未开启 namespace_auto_create 时未知的命名空间返回 404。
+func (s *Server) resolveNamespace(w http.ResponseWriter, r *http.Request) (*namespace, bool) {
+	requested := r.Header.Get(namespaceHeader)
+
+	if key := requestAPIKey(r); key != "" {
+		for _, nsCfg := range s.cfg.Namespaces {
+			if nsCfg.APIKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(nsCfg.APIKey)) != 1 {
+				continue
+			}
+			if requested != "" && requested != nsCfg.Name {
+				http.Error(w, fmt.Sprintf("API key does not grant access to namespace %s", requested), http.StatusForbidden)
+				return nil, false
+			}
+			return s.openNamespace(nsCfg.Name), true
+		}
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	name := requested
+	if name == "" {
+		name = config.DefaultNamespace
+	}
+	if !config.ValidNamespace(name) {
+		http.Error(w, fmt.Sprintf("Invalid namespace %q", name), http.StatusBadRequest)
+		return nil, false
+	}
+	if nsCfg, ok := s.cfg.Namespace(name); ok && nsCfg.APIKey != "" {
+		http.Error(w, fmt.Sprintf("Namespace %s requires an API key", name), http.StatusUnauthorized)
+		return nil, false
+	}
+	if ns, ok := s.lookupNamespace(name); ok {
+		return ns, true
+	}
+	if !s.cfg.NamespaceAutoCreate {
+		http.Error(w, fmt.Sprintf("Unknown namespace %s", name), http.StatusNotFound)
+		return nil, false
+	}
+	log.Printf("✓ Creating namespace %s on first use", name)
+	return s.openNamespace(name), true
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"cerebellum/internal/digest"
+	"cerebellum/internal/task"
+)
+
+// latestReport 始终指向最新报告的文件名
+const latestReport = "latest.md"
+
+// reportTimeFormat 报告文件名中的时间格式
+const reportTimeFormat = "report-20060102-150405.md"
+
+var reportNamePattern = regexp.MustCompile(`^report-\d{8}-\d{6}\.md$`)
+
+// digestTimeout 生成报告中摘要的最长时间
+const digestTimeout = 2 * time.Minute
+
+// ReportFile 已生成的报告文件
+type ReportFile struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// StartReporter 按 reports.interval 为每个命名空间生成 Markdown 报告（阻塞），未配置时立即返回
+func (s *Server) StartReporter() {
+	if s.cfg.Reports.Interval == "" {
+		return
+	}
+	interval, _ := time.ParseDuration(s.cfg.Reports.Interval)
+	log.Printf("✓ Writing reports every %s to %s", interval, s.cfg.Reports.Dir)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, ns := range s.namespaceList() {
+			if _, err := s.writeReport(ns, now, interval); err != nil {
+				log.Printf("Warning: Failed to write report for namespace %s: %v", ns.name, err)
+			}
+		}
+	}
+}
+
+// writeReport 写入一份报告并更新 latest.md，按 reports.keep 删除旧报告
+//
+// window 是附加摘要覆盖的时间段。
+func (s *Server) writeReport(ns *namespace, now time.Time, window time.Duration) (string, error) {
+	dir := s.cfg.Reports.NamespaceDir(ns.name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+
+	var extra []string
+	if s.cfg.Reports.Digest {
+		in, err := s.digestInput(ns, now.Add(-window), now)
+		if err != nil {
+			return "", fmt.Errorf("failed to collect runs: %w", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), digestTimeout)
+		report := digest.Build(ctx, s.generateDigest, in, s.cfg.Digest.MaxTokens)
+		cancel()
+		extra = append(extra, "## Digest\n\n```\n"+report.Text+"```\n")
+	}
+
+	report := ns.planner.GetReport()
+	name := now.Format(reportTimeFormat)
+	if err := task.WriteReportToFile(report, filepath.Join(dir, name), extra...); err != nil {
+		return "", err
+	}
+	if err := task.WriteReportToFile(report, filepath.Join(dir, latestReport), extra...); err != nil {
+		return "", err
+	}
+
+	if err := rotateReports(dir, s.cfg.Reports.Keep); err != nil {
+		log.Printf("Warning: Failed to rotate reports in %s: %v", dir, err)
+	}
+	return name, nil
+}
+
+// listReports 返回目录中的报告，最新的在前
+func listReports(dir string) ([]ReportFile, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []ReportFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]ReportFile, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !reportNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		generated, _ := time.ParseInLocation(reportTimeFormat, entry.Name(), time.Local)
+		reports = append(reports, ReportFile{Name: entry.Name(), Size: info.Size(), GeneratedAt: generated})
+	}
+	// 文件名中的时间可按字典序比较
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Name > reports[j].Name })
+	return reports, nil
+}
+
+// rotateReports 只保留最新的 keep 份报告
+func rotateReports(dir string, keep int) error {
+	reports, err := listReports(dir)
+	if err != nil {
+		return err
+	}
+	for _, report := range reports[min(keep, len(reports)):] {
+		if err := os.Remove(filepath.Join(dir, report.Name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleAPIReports /api/reports[/name] - 本命名空间的 Markdown 报告
+//
+//	GET  /api/reports         列出报告（最新的在前）
+//	GET  /api/reports/{name}  获取报告内容，latest 表示最新一份
+//	POST /api/reports         立即生成一份报告
+func (s *Server) HandleAPIReports(w http.ResponseWriter, r *http.Request) {
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	dir := s.cfg.Reports.NamespaceDir(ns.name)
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/reports"), "/")
+
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		reports, err := listReports(dir)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to list reports: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"namespace": ns.name,
+			"dir":       dir,
+			"reports":   reports,
+			"count":     len(reports),
+		})
+
+	case name == "" && r.Method == http.MethodPost:
+		window := 24 * time.Hour
+		if s.cfg.Reports.Interval != "" {
+			window, _ = time.ParseDuration(s.cfg.Reports.Interval)
+		}
+		created, err := s.writeReport(ns, time.Now(), window)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to write report: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "created",
+			"name":   created,
+		})
+
+	case name != "" && r.Method == http.MethodGet:
+		if name == "latest" {
+			name = latestReport
+		}
+		if name != latestReport && !reportNamePattern.MatchString(name) {
+			http.Error(w, "Invalid report name", http.StatusBadRequest)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			http.Error(w, "Report not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read report: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write(data)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
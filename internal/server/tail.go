@@ -0,0 +1,193 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cerebellum/internal/memory"
+)
+
+// HandleTailMemory GET /api/memory/tail - 以 SSE 推送新写入的记忆
+//
+// 参数（均可选）：
+//   - type      条目类型，可重复或逗号分隔，如 type=task_completed,task_failed
+//   - task_id   任务 ID
+//   - q         内容包含该子串（不区分大小写）
+//   - regex     内容匹配正则（RE2 语法）
+//   - backlog   先推送最近的 N 条已有记忆（最多 1000）
+//   - since     先推送该时间之后的已有记忆（RFC3339 或 Unix 秒）
+//
+// 每条记忆是一个 memory 事件，id 与 /api/memory 的分页游标格式相同；重连时从 Last-Event-ID
+// 继续，补发断开期间的记忆。补发超过 1000 条或订阅者跟不上时发送 gap 事件。
+func (s *Server) HandleTailMemory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if ns.memory == nil {
+		http.Error(w, "Memory system not initialized", http.StatusInternalServerError)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	var filter memory.TailFilter
+	for _, v := range query["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+	filter.TaskID = query.Get("task_id")
+	filter.Contains = query.Get("q")
+	if v := query.Get("regex"); v != "" {
+		var err error
+		if filter.Pattern, err = regexp.Compile(v); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid regex: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	backlog := 0
+	if v := query.Get("backlog"); v != "" {
+		var err error
+		if backlog, err = strconv.Atoi(v); err != nil || backlog < 0 || backlog > memory.MaxSearchLimit {
+			http.Error(w, fmt.Sprintf("Invalid backlog (0-%d)", memory.MaxSearchLimit), http.StatusBadRequest)
+			return
+		}
+	}
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid since: %v", err), http.StatusBadRequest)
+		return
+	}
+	var ids eventIDs
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor != "" {
+		at, seen, err := memory.ParseCursor(cursor)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		ids = eventIDs{at: at, seen: seen}
+		since, backlog = time.Time{}, 0
+	}
+
+	// 先订阅再读取已有记忆，两者之间写入的条目不会遗漏，重复的按内容去掉
+	sub := ns.memory.Subscribe(filter, memory.DefaultTailBuffer)
+	defer sub.Close()
+
+	search := memory.Search{Types: filter.Types, TaskID: filter.TaskID, Contains: filter.Contains, Pattern: filter.Pattern}
+	var earlier []memory.MemoryEntry
+	more := false
+	switch {
+	case cursor != "":
+		search.Cursor, search.Limit = cursor, memory.MaxSearchLimit
+		page, err := ns.memory.Search(search)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read memory: %v", err), memoryErrorStatus(err))
+			return
+		}
+		earlier, more = page.Entries, page.NextCursor != ""
+	case !since.IsZero():
+		search.Since, search.Limit = since, memory.MaxSearchLimit
+		page, err := ns.memory.Search(search)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read memory: %v", err), memoryErrorStatus(err))
+			return
+		}
+		earlier, more = page.Entries, page.NextCursor != ""
+	case backlog > 0:
+		search.Limit, search.Latest = backlog, true
+		page, err := ns.memory.Search(search)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read memory: %v", err), memoryErrorStatus(err))
+			return
+		}
+		earlier = page.Entries
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	sent := make(map[string]bool, len(earlier))
+	for _, entry := range earlier {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		sent[string(data)] = true
+		writeMemoryEvent(w, ids.next(entry), data)
+	}
+	if more {
+		fmt.Fprint(w, "event: gap\ndata: {\"reason\":\"backlog truncated\"}\n\n")
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case entry, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if dropped := sub.TakeDropped(); dropped > 0 {
+				fmt.Fprintf(w, "event: gap\ndata: {\"reason\":\"slow consumer\",\"dropped\":%d}\n\n", dropped)
+			}
+			data, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			if sent[string(data)] {
+				delete(sent, string(data))
+				continue
+			}
+			writeMemoryEvent(w, ids.next(entry), data)
+			flusher.Flush()
+		}
+	}
+}
+
+// eventIDs 生成事件 id：条目时间戳加上该时间戳已推送的条数，即 memory.Search 的游标
+type eventIDs struct {
+	at   time.Time
+	seen int
+}
+
+// next 返回该条目的事件 id
+func (ids *eventIDs) next(entry memory.MemoryEntry) string {
+	if entry.Timestamp.Equal(ids.at) {
+		ids.seen++
+	} else {
+		ids.at, ids.seen = entry.Timestamp, 1
+	}
+	return memory.FormatCursor(ids.at, ids.seen)
+}
+
+// writeMemoryEvent 写入一条 memory 事件
+func writeMemoryEvent(w http.ResponseWriter, id string, data []byte) {
+	fmt.Fprintf(w, "id: %s\nevent: memory\ndata: %s\n\n", id, data)
+}
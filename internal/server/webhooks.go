@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"cerebellum/internal/config"
+	"cerebellum/internal/notify"
+)
+
+// newNotifier 根据配置创建 webhook 通知器，没有配置 webhook 时返回 nil
+func newNotifier(cfg *config.Config) *notify.Notifier {
+	if len(cfg.Webhooks) == 0 {
+		return nil
+	}
+
+	targets := make([]notify.Target, 0, len(cfg.Webhooks))
+	for _, wh := range cfg.Webhooks {
+		timeout, _ := time.ParseDuration(wh.Timeout)
+		targets = append(targets, notify.Target{
+			Name:        wh.Name,
+			URL:         wh.URL,
+			Secret:      wh.Secret,
+			Namespaces:  wh.Namespaces,
+			MaxAttempts: wh.MaxAttempts,
+			Timeout:     timeout,
+		})
+	}
+
+	n, err := notify.New(filepath.Join(cfg.Storage.DataDir, "webhooks"), targets)
+	if err != nil {
+		log.Printf("Warning: Failed to initialize webhooks: %v", err)
+		return nil
+	}
+	log.Printf("✓ %d webhook targets configured", len(targets))
+	return n
+}
+
+// StartNotifier 启动 webhook 投递（阻塞），没有配置 webhook 时立即返回
+func (s *Server) StartNotifier() {
+	if s.notifier == nil {
+		return
+	}
+	s.notifier.Run()
+}
+
+// HandleAPIWebhookDeliveries GET /api/webhooks/deliveries - 查询本命名空间的 webhook 投递记录
+//
+// 参数：webhook、result（delivered/retrying/failed）、since、limit（默认 100）
+func (s *Server) HandleAPIWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ns, ok := s.resolveNamespace(w, r)
+	if !ok {
+		return
+	}
+	if s.notifier == nil {
+		http.Error(w, "No webhooks configured", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	q := notify.LogQuery{
+		Namespace: ns.name,
+		Webhook:   query.Get("webhook"),
+		Result:    query.Get("result"),
+		Limit:     100,
+	}
+	var err error
+	if q.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid since: %v", err), http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	attempts, err := s.notifier.Log(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"namespace":  ns.name,
+		"webhooks":   s.notifier.WebhookNames(ns.name),
+		"pending":    s.notifier.Pending(ns.name),
+		"deliveries": attempts,
+		"count":      len(attempts),
+	})
+}
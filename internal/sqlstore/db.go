@@ -0,0 +1,87 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS tasks (
+	id         TEXT PRIMARY KEY,
+	type       TEXT NOT NULL,
+	data       TEXT NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS task_runs (
+	task_id    TEXT NOT NULL,
+	run_id     TEXT NOT NULL,
+	started_at INTEGER NOT NULL,
+	data       TEXT NOT NULL,
+	PRIMARY KEY (task_id, run_id)
+);
+CREATE INDEX IF NOT EXISTS idx_task_runs_started ON task_runs (task_id, started_at);
+
+CREATE TABLE IF NOT EXISTS memory (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	ts      INTEGER NOT NULL,
+	type    TEXT NOT NULL,
+	task_id TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL,
+	data    TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_memory_ts ON memory (ts);
+CREATE INDEX IF NOT EXISTS idx_memory_type_ts ON memory (type, ts);
+CREATE INDEX IF NOT EXISTS idx_memory_task_ts ON memory (task_id, ts);
+`
+
+// DB 嵌入式 SQLite 存储（纯 Go 驱动，无需 cgo）
+type DB struct {
+	db      *sql.DB
+	maxRuns int
+}
+
+// Open 打开（或创建）SQLite 数据库并初始化表结构
+func Open(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// SQLite 同一时间只允许一个写入者，单连接避免 "database is locked"
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize sqlite schema: %w", err)
+	}
+
+	return &DB{db: db, maxRuns: 200}, nil
+}
+
+// Close 关闭数据库
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// SetMaxRuns 设置每个任务保留的最大执行记录数
+func (d *DB) SetMaxRuns(n int) {
+	d.maxRuns = n
+}
+
+// Tasks 返回任务存储（实现 task.Store）
+func (d *DB) Tasks() *TaskStore {
+	return &TaskStore{d: d}
+}
+
+// Memory 返回记忆存储后端（实现 memory.Backend）
+func (d *DB) Memory() *MemoryBackend {
+	return &MemoryBackend{d: d}
+}
@@ -0,0 +1,162 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cerebellum/internal/memory"
+)
+
+// MemoryBackend 将记忆条目保存在 SQLite 中
+type MemoryBackend struct {
+	d *DB
+}
+
+// Append 写入一条记忆
+func (b *MemoryBackend) Append(entry memory.MemoryEntry) error {
+	return insertEntry(b.d.db, entry)
+}
+
+// Import 在一个事务中批量写入记忆（用于迁移）
+func (b *MemoryBackend) Import(entries []memory.MemoryEntry) error {
+	tx, err := b.d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, entry := range entries {
+		if err := insertEntry(tx, entry); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit memory: %w", err)
+	}
+	return nil
+}
+
+// insertEntry 写入单条记忆
+func insertEntry(ex execer, entry memory.MemoryEntry) error {
+	var data interface{}
+	if len(entry.Data) > 0 {
+		data = string(entry.Data)
+	}
+	_, err := ex.Exec(`INSERT INTO memory (ts, type, task_id, content, data) VALUES (?, ?, ?, ?, ?)`,
+		entry.Timestamp.UnixNano(), entry.Type, entry.TaskID, entry.Content, data)
+	if err != nil {
+		return fmt.Errorf("failed to write memory entry: %w", err)
+	}
+	return nil
+}
+
+// Query 按条件查询记忆，结果按时间正序
+func (b *MemoryBackend) Query(q memory.Query) ([]memory.MemoryEntry, error) {
+	var conds []string
+	var args []interface{}
+	if len(q.Types) > 0 {
+		conds = append(conds, `type IN (?`+strings.Repeat(`, ?`, len(q.Types)-1)+`)`)
+		for _, t := range q.Types {
+			args = append(args, t)
+		}
+	}
+	if q.TaskID != "" {
+		conds = append(conds, `task_id = ?`)
+		args = append(args, q.TaskID)
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, `ts >= ?`)
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, `ts < ?`)
+		args = append(args, q.Until.UnixNano())
+	}
+
+	query := `SELECT ts, type, task_id, content, data FROM memory`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, ` AND `)
+	}
+	if q.Newest {
+		query += ` ORDER BY ts DESC, id DESC`
+	} else {
+		query += ` ORDER BY ts ASC, id ASC`
+	}
+	// 内容过滤在读取后进行，此时不能在 SQL 中截取
+	if q.Limit > 0 && q.Match == nil {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := b.d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []memory.MemoryEntry
+	for rows.Next() {
+		var ts int64
+		var data sql.NullString
+		var entry memory.MemoryEntry
+		if err := rows.Scan(&ts, &entry.Type, &entry.TaskID, &entry.Content, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan memory entry: %w", err)
+		}
+		entry.Timestamp = time.Unix(0, ts)
+		if data.Valid {
+			entry.Data = json.RawMessage(data.String)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read memory: %w", err)
+	}
+
+	if q.Newest {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	return q.FilterMatch(entries), nil
+}
+
+// Stats 按类型统计记忆条数
+func (b *MemoryBackend) Stats() (map[string]int, error) {
+	rows, err := b.d.db.Query(`SELECT type, COUNT(*) FROM memory GROUP BY type`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]int)
+	for rows.Next() {
+		var t string
+		var n int
+		if err := rows.Scan(&t, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan memory stats: %w", err)
+		}
+		stats[t] = n
+	}
+	return stats, rows.Err()
+}
+
+// Count 返回记忆总条数
+func (b *MemoryBackend) Count() (int, error) {
+	var n int
+	if err := b.d.db.QueryRow(`SELECT COUNT(*) FROM memory`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count memory: %w", err)
+	}
+	return n, nil
+}
+
+// Clear 删除全部记忆
+func (b *MemoryBackend) Clear() error {
+	if _, err := b.d.db.Exec(`DELETE FROM memory`); err != nil {
+		return fmt.Errorf("failed to clear memory: %w", err)
+	}
+	return nil
+}
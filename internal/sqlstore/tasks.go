@@ -0,0 +1,190 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"cerebellum/internal/task"
+)
+
+// TaskStore 将任务和执行历史保存在 SQLite 中
+type TaskStore struct {
+	d *DB
+}
+
+// Load 读取全部任务
+func (s *TaskStore) Load() (map[string]*task.TaskPlan, map[string]*task.TaskPlan, *task.RecoveryReport, error) {
+	report := &task.RecoveryReport{LoadedAt: time.Now()}
+	periodic := make(map[string]*task.TaskPlan)
+	once := make(map[string]*task.TaskPlan)
+
+	rows, err := s.d.db.Query(`SELECT id, data FROM tasks`)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		var plan task.TaskPlan
+		if err := json.Unmarshal([]byte(data), &plan); err != nil {
+			report.SnapshotProblems = append(report.SnapshotProblems, fmt.Sprintf("task %s: %v", id, err))
+			continue
+		}
+		if plan.Type == task.TaskTypePeriodic {
+			periodic[id] = &plan
+		} else {
+			once[id] = &plan
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read tasks: %w", err)
+	}
+
+	report.SnapshotTasks = len(periodic) + len(once)
+	return periodic, once, report, nil
+}
+
+// Put 插入或更新任务
+func (s *TaskStore) Put(plan *task.TaskPlan) error {
+	return putTask(s.d.db, plan)
+}
+
+// Delete 删除任务
+func (s *TaskStore) Delete(id string) error {
+	if _, err := s.d.db.Exec(`DELETE FROM tasks WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
+	}
+	return nil
+}
+
+// Checkpoint 在一个事务中用完整快照替换任务表
+func (s *TaskStore) Checkpoint(periodic, once map[string]*task.TaskPlan) error {
+	tx, err := s.d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM tasks`); err != nil {
+		return fmt.Errorf("failed to clear tasks: %w", err)
+	}
+	for _, tasks := range []map[string]*task.TaskPlan{periodic, once} {
+		for _, plan := range tasks {
+			if err := putTask(tx, plan); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tasks: %w", err)
+	}
+	return nil
+}
+
+// execer 兼容 *sql.DB 与 *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// putTask 写入单个任务
+func putTask(ex execer, plan *task.TaskPlan) error {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	_, err = ex.Exec(`INSERT INTO tasks (id, type, data, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET type = excluded.type, data = excluded.data, updated_at = excluded.updated_at`,
+		plan.ID, string(plan.Type), string(data), time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to write task: %w", err)
+	}
+	return nil
+}
+
+// AppendRun 写入执行记录，超过上限时删除最早的记录
+func (s *TaskStore) AppendRun(run task.TaskRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+
+	tx, err := s.d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO task_runs (task_id, run_id, started_at, data) VALUES (?, ?, ?, ?)`,
+		run.TaskID, run.RunID, run.StartedAt.UnixNano(), string(data)); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM task_runs WHERE task_id = ? AND run_id NOT IN (
+		SELECT run_id FROM task_runs WHERE task_id = ? ORDER BY started_at DESC LIMIT ?)`,
+		run.TaskID, run.TaskID, s.d.maxRuns); err != nil {
+		return fmt.Errorf("failed to trim runs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit run: %w", err)
+	}
+	return nil
+}
+
+// ListRuns 按时间倒序查询执行记录及满足条件的总数
+func (s *TaskStore) ListRuns(taskID string, q task.RunQuery) ([]task.TaskRun, int, error) {
+	where := `task_id = ?`
+	args := []interface{}{taskID}
+	if !q.Since.IsZero() {
+		where += ` AND started_at >= ?`
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where += ` AND started_at <= ?`
+		args = append(args, q.Until.UnixNano())
+	}
+
+	var total int
+	if err := s.d.db.QueryRow(`SELECT COUNT(*) FROM task_runs WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count runs: %w", err)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.d.db.Query(`SELECT data FROM task_runs WHERE `+where+` ORDER BY started_at DESC LIMIT ? OFFSET ?`,
+		append(args, limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]task.TaskRun, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan run: %w", err)
+		}
+		var run task.TaskRun
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, total, rows.Err()
+}
+
+// RemoveRuns 删除任务的全部执行记录
+func (s *TaskStore) RemoveRuns(taskID string) error {
+	if _, err := s.d.db.Exec(`DELETE FROM task_runs WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("failed to remove runs: %w", err)
+	}
+	return nil
+}
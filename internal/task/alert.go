@@ -0,0 +1,216 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConditionType 告警条件类型
+type ConditionType string
+
+const (
+	ConditionChanged   ConditionType = "changed"   // 结果与上一次不同
+	ConditionThreshold ConditionType = "threshold" // 数值越过阈值
+	ConditionRegex     ConditionType = "regex"     // 结果匹配正则
+	ConditionLLM       ConditionType = "llm"       // 由本地模型判断是否重要
+)
+
+// AlertCondition 周期任务结果的告警条件，任一条件满足即向大脑发出 alert
+type AlertCondition struct {
+	Type    ConditionType `json:"type"`
+	Above   *float64      `json:"above,omitempty"`   // threshold: 数值由不高于变为高于该值
+	Below   *float64      `json:"below,omitempty"`   // threshold: 数值由不低于变为低于该值
+	Pattern string        `json:"pattern,omitempty"` // regex: 匹配模式；threshold: 可选，首个分组为数值
+	Prompt  string        `json:"prompt,omitempty"`  // llm: 判断标准，如 "价格是否出现异常波动"
+}
+
+// Judge 用本地模型回答判断题
+type Judge func(ctx context.Context, prompt string) (string, error)
+
+// judgeTimeout 一次执行中 llm 条件判断的最长时间
+const judgeTimeout = 30 * time.Second
+
+var numberPattern = regexp.MustCompile(`-?\d[\d,]*(?:\.\d+)?`)
+
+// maxCachedPatterns 编译结果缓存的上限，超过后不再缓存新模式
+const maxCachedPatterns = 1024
+
+// patternCache 条件和指标的正则编译结果，校验时写入，执行时直接使用
+var patternCache = struct {
+	sync.RWMutex
+	m map[string]*regexp.Regexp
+}{m: make(map[string]*regexp.Regexp)}
+
+// compilePattern 返回编译后的正则，同一模式只编译一次
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternCache.RLock()
+	re, ok := patternCache.m[pattern]
+	patternCache.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Lock()
+	if len(patternCache.m) < maxCachedPatterns {
+		patternCache.m[pattern] = re
+	}
+	patternCache.Unlock()
+	return re, nil
+}
+
+// Validate 检查条件配置是否有效
+func (c AlertCondition) Validate() error {
+	switch c.Type {
+	case ConditionChanged:
+	case ConditionThreshold:
+		if c.Above == nil && c.Below == nil {
+			return fmt.Errorf("threshold condition requires above or below")
+		}
+		if c.Pattern != "" {
+			if _, err := compilePattern(c.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern: %w", err)
+			}
+		}
+	case ConditionRegex:
+		if c.Pattern == "" {
+			return fmt.Errorf("regex condition requires pattern")
+		}
+		if _, err := compilePattern(c.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case ConditionLLM:
+		if c.Prompt == "" {
+			return fmt.Errorf("llm condition requires prompt")
+		}
+	default:
+		return fmt.Errorf("unknown condition type %q", c.Type)
+	}
+	return nil
+}
+
+// ValidateConditions 检查一组条件
+func ValidateConditions(conds []AlertCondition) error {
+	for i, c := range conds {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("condition %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// evaluateConditions 返回满足的条件说明，为空表示本次结果不需要告警
+func evaluateConditions(ctx context.Context, conds []AlertCondition, prev, curr string, judge Judge) []string {
+	var reasons []string
+	for _, c := range conds {
+		if reason, ok := c.match(ctx, prev, curr, judge); ok {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// match 判断单个条件
+func (c AlertCondition) match(ctx context.Context, prev, curr string, judge Judge) (string, bool) {
+	switch c.Type {
+	case ConditionChanged:
+		// 第一次执行没有可比较的结果
+		if prev != "" && strings.TrimSpace(prev) != strings.TrimSpace(curr) {
+			return "result changed", true
+		}
+	case ConditionThreshold:
+		value, ok := c.extractNumber(curr)
+		if !ok {
+			return "", false
+		}
+		last, hasLast := c.extractNumber(prev)
+		if c.Above != nil && value > *c.Above && (!hasLast || last <= *c.Above) {
+			return fmt.Sprintf("value %g crossed above %g", value, *c.Above), true
+		}
+		if c.Below != nil && value < *c.Below && (!hasLast || last >= *c.Below) {
+			return fmt.Sprintf("value %g crossed below %g", value, *c.Below), true
+		}
+	case ConditionRegex:
+		re, err := compilePattern(c.Pattern)
+		if err == nil && re.MatchString(curr) {
+			return fmt.Sprintf("result matched %q", c.Pattern), true
+		}
+	case ConditionLLM:
+		if judge == nil {
+			return "", false
+		}
+		prompt := fmt.Sprintf(`<system_instructions>
+You are Cerebellum alert judge. Decide whether the latest task result is important enough to report to the brain.
+
+Criterion: %s
+
+Previous result:
+%s
+
+Latest result:
+%s
+
+Answer with YES or NO on the first line, then one short sentence explaining why.
+</system_instructions>`, c.Prompt, prev, curr)
+		answer, err := judge(ctx, prompt)
+		if err != nil {
+			return "", false
+		}
+		answer = strings.TrimSpace(answer)
+		upper := strings.ToUpper(answer)
+		if strings.HasPrefix(upper, "YES") || strings.HasPrefix(answer, "是") {
+			return fmt.Sprintf("llm judged important: %s", firstLine(answer)), true
+		}
+	}
+	return "", false
+}
+
+// extractNumber 从结果中提取数值；设置了 Pattern 时取首个分组（或整个匹配）
+func (c AlertCondition) extractNumber(text string) (float64, bool) {
+	return extractNumber(c.Pattern, text)
+}
+
+// extractNumber 从文本中提取第一个数值；pattern 不为空时先取其首个分组（或整个匹配）
+func extractNumber(pattern, text string) (float64, bool) {
+	if text == "" {
+		return 0, false
+	}
+	if pattern != "" {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return 0, false
+		}
+		m := re.FindStringSubmatch(text)
+		if m == nil {
+			return 0, false
+		}
+		text = m[0]
+		if len(m) > 1 {
+			text = m[1]
+		}
+	}
+	raw := numberPattern.FindString(text)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(raw, ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// firstLine 返回文本的第一行
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[:i])
+	}
+	return s
+}
@@ -0,0 +1,331 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	// ErrTaskNotFound 任务不存在
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrTaskRunning 任务正在执行，无法进行该操作
+	ErrTaskRunning = errors.New("task is running")
+	// ErrInvalidOperation 当前任务类型或状态不支持该操作
+	ErrInvalidOperation = errors.New("operation not allowed for task")
+	// ErrVersionConflict if_match 与任务当前版本不一致
+	ErrVersionConflict = errors.New("task version conflict")
+)
+
+// TaskPatch 大脑对已有任务的修改，nil 字段保持不变
+type TaskPatch struct {
+	Command    *string           `json:"command,omitempty"`
+	Interval   *string           `json:"interval,omitempty"`
+	Conditions *[]AlertCondition `json:"conditions,omitempty"`
+	Metrics    *[]MetricSpec     `json:"metrics,omitempty"`
+	ExpiresAt  *time.Time        `json:"expires_at,omitempty"`
+	MaxRuns    *int              `json:"max_runs,omitempty"`
+	Retention  *string           `json:"retention,omitempty"`
+	Priority   *int              `json:"priority,omitempty"`
+	Owner      *string           `json:"owner,omitempty"`
+	IfMatch    *int              `json:"if_match,omitempty"`
+}
+
+// findTask 在两类任务中查找（调用方需持有 g.mu）
+func (g *PlanGenerator) findTask(id string) *TaskPlan {
+	if task, ok := g.periodicTasks[id]; ok {
+		return task
+	}
+	if task, ok := g.onceTasks[id]; ok {
+		return task
+	}
+	return nil
+}
+
+// GetTask 获取任务计划的副本
+func (g *PlanGenerator) GetTask(id string) (*TaskPlan, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findTask(id)
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	return copyPlan(task), nil
+}
+
+// copyPlan 复制任务计划，避免调用方共享内部切片
+func copyPlan(task *TaskPlan) *TaskPlan {
+	plan := *task
+	plan.Conditions = append([]AlertCondition(nil), task.Conditions...)
+	plan.Metrics = copyMetrics(task.Metrics)
+	if task.Lease != nil {
+		lease := *task.Lease
+		plan.Lease = &lease
+	}
+	if task.Decision != nil {
+		plan.Decision = copyDecision(task.Decision)
+	}
+	return &plan
+}
+
+// UpdateTask 修改任务的命令、间隔、告警条件、指标或生命周期设置
+func (g *PlanGenerator) UpdateTask(id string, patch TaskPatch) (*TaskPlan, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findTask(id)
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	if patch.IfMatch != nil && *patch.IfMatch != task.Version {
+		return nil, fmt.Errorf("%w: expected version %d, current %d", ErrVersionConflict, *patch.IfMatch, task.Version)
+	}
+
+	if patch.Interval != nil {
+		if task.Type != TaskTypePeriodic {
+			return nil, fmt.Errorf("%w: interval only applies to periodic tasks", ErrInvalidOperation)
+		}
+		if _, err := time.ParseDuration(*patch.Interval); err != nil {
+			return nil, fmt.Errorf("%w: invalid interval %q", ErrInvalidOperation, *patch.Interval)
+		}
+	}
+	if patch.Command != nil && *patch.Command == "" {
+		return nil, fmt.Errorf("%w: command cannot be empty", ErrInvalidOperation)
+	}
+	if patch.Conditions != nil {
+		if task.Type != TaskTypePeriodic {
+			return nil, fmt.Errorf("%w: conditions only apply to periodic tasks", ErrInvalidOperation)
+		}
+		if err := ValidateConditions(*patch.Conditions); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+		}
+	}
+	if patch.Metrics != nil {
+		if err := ValidateMetrics(*patch.Metrics); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+		}
+	}
+	if patch.MaxRuns != nil && *patch.MaxRuns < 0 {
+		return nil, fmt.Errorf("%w: max_runs cannot be negative", ErrInvalidOperation)
+	}
+	if patch.Retention != nil && *patch.Retention != "" {
+		if _, err := time.ParseDuration(*patch.Retention); err != nil {
+			return nil, fmt.Errorf("%w: invalid retention %q", ErrInvalidOperation, *patch.Retention)
+		}
+	}
+
+	oldStatus := task.Status
+	if patch.Command != nil && *patch.Command != task.Command {
+		task.Command = *patch.Command
+		// 与 upsert 一致：已结束的一次性任务换了命令，重新执行
+		if task.Type == TaskTypeOnce && task.Status != "running" {
+			task.Status = "pending"
+			task.NextRun = time.Now()
+		}
+	}
+	if patch.Interval != nil && *patch.Interval != task.Interval {
+		task.Interval = *patch.Interval
+		task.NextRun = g.calcNextRun(task.Interval, time.Now())
+	}
+	if patch.Conditions != nil {
+		task.Conditions = *patch.Conditions
+	}
+	if patch.Metrics != nil {
+		task.Metrics = copyMetrics(*patch.Metrics)
+	}
+	if patch.ExpiresAt != nil {
+		task.ExpiresAt = patch.ExpiresAt
+	}
+	if patch.MaxRuns != nil {
+		task.MaxRuns = *patch.MaxRuns
+	}
+	if patch.Retention != nil {
+		task.Retention = *patch.Retention
+	}
+	if patch.Priority != nil {
+		task.Priority = *patch.Priority
+	}
+	if patch.Owner != nil {
+		task.Owner = *patch.Owner
+	}
+	task.Version++
+	g.journalPut(task)
+
+	g.recordChange(ChangeTypeUpdated, id, oldStatus, task.Status)
+	if g.memory != nil {
+		g.memory.Write("task_updated", id,
+			fmt.Sprintf("Task updated: %s (interval: %s)", task.Command, task.Interval),
+			patch)
+	}
+
+	return copyPlan(task), nil
+}
+
+// PauseTask 暂停周期任务，正在进行的执行会继续完成
+func (g *PlanGenerator) PauseTask(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findTask(id)
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	if task.Type != TaskTypePeriodic {
+		return fmt.Errorf("%w: only periodic tasks can be paused", ErrInvalidOperation)
+	}
+	if task.Paused {
+		return nil
+	}
+
+	oldStatus := task.Status
+	task.Paused = true
+	if task.Status != "running" {
+		task.Status = "paused"
+	}
+	g.recordChange(ChangeTypePaused, id, oldStatus, task.Status)
+
+	if g.memory != nil {
+		g.memory.Write("task_paused", id, "Periodic task paused", nil)
+	}
+	g.journalPut(task)
+	return nil
+}
+
+// ResumeTask 恢复已暂停的周期任务，在下一个执行周期立即运行
+func (g *PlanGenerator) ResumeTask(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findTask(id)
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	if task.Type != TaskTypePeriodic {
+		return fmt.Errorf("%w: only periodic tasks can be resumed", ErrInvalidOperation)
+	}
+	if !task.Paused {
+		return nil
+	}
+
+	oldStatus := task.Status
+	task.Paused = false
+	if task.Status != "running" {
+		task.Status = "pending"
+		task.NextRun = time.Now()
+	}
+	g.recordChange(ChangeTypeResumed, id, oldStatus, task.Status)
+
+	if g.memory != nil {
+		g.memory.Write("task_resumed", id, "Periodic task resumed", nil)
+	}
+	g.journalPut(task)
+	return nil
+}
+
+// CancelTask 取消任务：中断正在进行的执行；未执行的一次性任务将不再执行
+func (g *PlanGenerator) CancelTask(id string) error {
+	if g.cancelRun(id, stopCancelled) {
+		// 状态由执行方在执行结束时更新
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findTask(id)
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	if task.Type != TaskTypeOnce || (task.Status != "pending" && task.Status != "failed" && task.Status != "waiting") {
+		return fmt.Errorf("%w: task is not running (status: %s)", ErrInvalidOperation, task.Status)
+	}
+
+	oldStatus := task.Status
+	task.Status = "cancelled"
+	task.FinishedAt = time.Now()
+	g.closeDecisionLocked(task, "task cancelled")
+	g.recordChange(ChangeTypeCancelled, id, oldStatus, "cancelled")
+
+	if g.memory != nil {
+		g.memory.Write("task_cancelled", id, "Task cancelled before execution", nil)
+	}
+	g.journalPut(task)
+	return nil
+}
+
+// TriggerTask 让任务在下一次执行时立即运行，已结束的一次性任务会重新执行
+func (g *PlanGenerator) TriggerTask(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findTask(id)
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	if task.Status == "running" {
+		return ErrTaskRunning
+	}
+	if task.Paused {
+		return fmt.Errorf("%w: task is paused", ErrInvalidOperation)
+	}
+
+	oldStatus := task.Status
+	task.NextRun = time.Now()
+	if task.Type == TaskTypeOnce {
+		task.Status = "pending"
+	}
+	g.recordChange(ChangeTypeUpdated, id, oldStatus, task.Status)
+
+	if g.memory != nil {
+		g.memory.Write("task_triggered", id, "Task triggered to run now", nil)
+	}
+	g.journalPut(task)
+	return nil
+}
+
+// RemoveTask 删除任务（周期任务或一次性任务），正在执行的任务需先取消
+func (g *PlanGenerator) RemoveTask(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findTask(id)
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	if task.Status == "running" {
+		return ErrTaskRunning
+	}
+
+	g.closeDecisionLocked(task, "task removed")
+	g.removeLocked(task)
+	g.recordChange(ChangeTypeRemoved, id, task.Status, "")
+
+	if g.memory != nil {
+		g.memory.Write("task_removed", id,
+			fmt.Sprintf("%s task removed", task.Type), nil)
+	}
+	return nil
+}
+
+// removeLocked 从计划中删除任务及其执行历史（调用方需持有 g.mu）
+func (g *PlanGenerator) removeLocked(task *TaskPlan) {
+	g.dropLocked(task)
+	if g.store != nil {
+		if err := g.store.RemoveRuns(task.ID); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+// dropLocked 从计划中删除任务，保留执行历史（调用方需持有 g.mu）
+func (g *PlanGenerator) dropLocked(task *TaskPlan) {
+	if task.Type == TaskTypePeriodic {
+		delete(g.periodicTasks, task.ID)
+	} else {
+		delete(g.onceTasks, task.ID)
+	}
+	g.taskCount = len(g.periodicTasks) + len(g.onceTasks)
+	g.journalDelete(task.ID)
+}
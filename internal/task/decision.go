@@ -0,0 +1,314 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 决策请求状态
+const (
+	DecisionPending  = "pending"  // 等待大脑回答
+	DecisionAnswered = "answered" // 已回答，任务下次执行时读取
+)
+
+// ErrDecisionNotFound 决策请求不存在
+var ErrDecisionNotFound = errors.New("decision not found")
+
+// decisionMarker 任务输出中请求大脑决策的标记，格式：
+//
+//	【需要决策】
+//	问题：是否参与这个讨论？
+//	选项：参与 / 忽略
+const decisionMarker = "【需要决策】"
+
+// maxDecisionContext 决策背景保留的最大字符数
+const maxDecisionContext = 2000
+
+// Decision 小脑向大脑提出的决策请求，挂在发起的任务上随任务持久化
+type Decision struct {
+	ID         string     `json:"id"`
+	TaskID     string     `json:"task_id"`
+	Question   string     `json:"question"`
+	Options    []string   `json:"options,omitempty"`
+	Context    string     `json:"context,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Answer     string     `json:"answer,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+	AnsweredAt *time.Time `json:"answered_at,omitempty"`
+	FollowUps  []string   `json:"follow_ups,omitempty"` // 回答时创建的后续任务
+}
+
+// CheckAnswer 检查回答是否有效：不能为空，有选项时必须是其中之一
+func (d *Decision) CheckAnswer(answer string) error {
+	if d.Status != DecisionPending {
+		return fmt.Errorf("%w: decision already %s", ErrInvalidOperation, d.Status)
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return fmt.Errorf("%w: answer is required", ErrInvalidOperation)
+	}
+	if len(d.Options) == 0 {
+		return nil
+	}
+	for _, option := range d.Options {
+		if strings.EqualFold(option, answer) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: answer must be one of %s", ErrInvalidOperation, strings.Join(d.Options, " / "))
+}
+
+// copyDecision 复制决策请求
+func copyDecision(d *Decision) *Decision {
+	decision := *d
+	decision.Options = append([]string(nil), d.Options...)
+	decision.FollowUps = append([]string(nil), d.FollowUps...)
+	if d.AnsweredAt != nil {
+		answered := *d.AnsweredAt
+		decision.AnsweredAt = &answered
+	}
+	return &decision
+}
+
+// parseDecisionRequest 从任务输出中解析决策请求，没有标记时返回 nil
+//
+// 标记之前的内容和标记之后无法识别的行作为决策背景。
+func parseDecisionRequest(output string) *Decision {
+	idx := strings.Index(output, decisionMarker)
+	if idx < 0 {
+		return nil
+	}
+
+	d := &Decision{}
+	context := []string{strings.TrimSpace(output[:idx])}
+	for _, line := range strings.Split(output[idx+len(decisionMarker):], "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if v, ok := cutField(line, "问题", "Question"); ok && d.Question == "" {
+			d.Question = v
+		} else if v, ok := cutField(line, "选项", "Options"); ok && len(d.Options) == 0 {
+			d.Options = splitOptions(v)
+		} else if d.Question == "" {
+			d.Question = line
+		} else {
+			context = append(context, line)
+		}
+	}
+	if d.Question == "" {
+		return nil
+	}
+
+	d.Context = strings.TrimSpace(strings.Join(context, "\n"))
+	if r := []rune(d.Context); len(r) > maxDecisionContext {
+		d.Context = string(r[:maxDecisionContext]) + "…"
+	}
+	return d
+}
+
+// cutField 解析 "名称：值" 形式的行，支持中英文冒号
+func cutField(line string, names ...string) (string, bool) {
+	for _, name := range names {
+		for _, sep := range []string{"：", ":"} {
+			if v, ok := strings.CutPrefix(line, name+sep); ok {
+				return strings.TrimSpace(v), true
+			}
+		}
+	}
+	return "", false
+}
+
+// splitOptions 按 "/"、"|" 或 "、" 拆分选项
+func splitOptions(v string) []string {
+	fields := strings.FieldsFunc(v, func(r rune) bool {
+		return r == '/' || r == '|' || r == '、'
+	})
+	options := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			options = append(options, f)
+		}
+	}
+	return options
+}
+
+// raiseDecisionLocked 在任务上挂起决策请求（调用方需持有 g.mu）
+//
+// 任务已有未回答的请求时忽略新请求，返回 false。一次性任务进入 waiting，
+// 回答后重新执行；周期任务照常运行，在回答后的下一次执行中读取决策。
+func (g *PlanGenerator) raiseDecisionLocked(task *TaskPlan, d *Decision, oldStatus string) bool {
+	if task.Decision != nil && task.Decision.Status == DecisionPending {
+		return false
+	}
+
+	now := time.Now()
+	d.ID = fmt.Sprintf("dec-%d", now.UnixNano())
+	d.TaskID = task.ID
+	d.Status = DecisionPending
+	d.CreatedAt = now
+	task.Decision = d
+	if task.Type == TaskTypeOnce {
+		task.Status = "waiting"
+	}
+
+	g.appendChange(TaskChange{
+		Type:      ChangeTypeDecision,
+		TaskID:    task.ID,
+		Timestamp: now,
+		OldStatus: oldStatus,
+		NewStatus: task.Status,
+		Result:    task.Result,
+		Reason:    d.Question,
+	})
+
+	if g.memory != nil {
+		g.memory.Write("decision_requested", task.ID,
+			fmt.Sprintf("Decision requested: %s", d.Question), d)
+	}
+	return true
+}
+
+// withDecision 将已回答的决策附加到任务命令中，供本次执行读取
+func withDecision(command string, d *Decision) string {
+	text := fmt.Sprintf("%s\n\n【大脑决策】\n问题：%s\n决策：%s", command, d.Question, d.Answer)
+	if d.Comment != "" {
+		text += "\n说明：" + d.Comment
+	}
+	return text
+}
+
+// deliverDecisionLocked 任务成功读取决策后将其移除（调用方需持有 g.mu）
+func (g *PlanGenerator) deliverDecisionLocked(task *TaskPlan, delivered *Decision) {
+	if delivered == nil || task.Decision != delivered {
+		return
+	}
+	task.Decision = nil
+
+	if g.memory != nil {
+		g.memory.Write("decision_delivered", task.ID,
+			fmt.Sprintf("Decision delivered: %s -> %s", delivered.Question, delivered.Answer), delivered)
+	}
+}
+
+// findDecision 按 ID 查找决策请求所在的任务（调用方需持有 g.mu）
+func (g *PlanGenerator) findDecision(id string) *TaskPlan {
+	for _, tasks := range []map[string]*TaskPlan{g.periodicTasks, g.onceTasks} {
+		for _, task := range tasks {
+			if task.Decision != nil && task.Decision.ID == id {
+				return task
+			}
+		}
+	}
+	return nil
+}
+
+// ListDecisions 列出决策请求，status 为空时返回全部，按创建时间正序
+func (g *PlanGenerator) ListDecisions(status string) []*Decision {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	decisions := make([]*Decision, 0)
+	for _, tasks := range []map[string]*TaskPlan{g.periodicTasks, g.onceTasks} {
+		for _, task := range tasks {
+			if task.Decision != nil && (status == "" || task.Decision.Status == status) {
+				decisions = append(decisions, copyDecision(task.Decision))
+			}
+		}
+	}
+	sort.Slice(decisions, func(i, j int) bool { return decisions[i].CreatedAt.Before(decisions[j].CreatedAt) })
+	return decisions
+}
+
+// GetDecision 获取决策请求的副本
+func (g *PlanGenerator) GetDecision(id string) (*Decision, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findDecision(id)
+	if task == nil {
+		return nil, ErrDecisionNotFound
+	}
+	return copyDecision(task.Decision), nil
+}
+
+// AnswerDecision 记录大脑的回答并创建或更新后续任务，等待中的一次性任务重新排队执行
+//
+// 检查回答、写入后续任务和记录回答在同一把锁内完成。后续任务不能改变发起决策的任务的类型；
+// 因冲突或配额未生效时不记录回答，返回的 Decision 为 nil，由 UpsertResult 说明原因，大脑可以修正后重试。
+func (g *PlanGenerator) AnswerDecision(id, answer, comment string, tasks []BrainTask) (*Decision, *UpsertResult, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	task := g.findDecision(id)
+	if task == nil {
+		return nil, nil, ErrDecisionNotFound
+	}
+	if err := task.Decision.CheckAnswer(answer); err != nil {
+		return nil, nil, err
+	}
+	for _, t := range tasks {
+		if t.ID == task.ID && t.Type != task.Type {
+			return nil, nil, fmt.Errorf("%w: follow-up task %s cannot change the type of the task awaiting this decision", ErrInvalidOperation, t.ID)
+		}
+	}
+
+	var result *UpsertResult
+	var followUps []string
+	if len(tasks) > 0 {
+		result = g.generatePlanLocked(tasks)
+		if result.Quota != nil || len(result.Conflicts) > 0 || len(result.Running) > 0 {
+			return nil, result, nil
+		}
+		for _, t := range tasks {
+			followUps = append(followUps, t.ID)
+		}
+	}
+	d := task.Decision
+
+	now := time.Now()
+	d.Status = DecisionAnswered
+	d.Answer = strings.TrimSpace(answer)
+	d.Comment = comment
+	d.AnsweredAt = &now
+	d.FollowUps = followUps
+
+	oldStatus := task.Status
+	if task.Type == TaskTypeOnce && task.Status == "waiting" {
+		task.Status = "pending"
+		task.NextRun = now
+	}
+	g.appendChange(TaskChange{
+		Type:      ChangeTypeUpdated,
+		TaskID:    task.ID,
+		Timestamp: now,
+		OldStatus: oldStatus,
+		NewStatus: task.Status,
+		Reason:    fmt.Sprintf("decision answered: %s", d.Answer),
+	})
+
+	if g.memory != nil {
+		g.memory.Write("decision_answered", task.ID,
+			fmt.Sprintf("Decision answered: %s -> %s", d.Question, d.Answer), d)
+	}
+	g.journalPut(task)
+	return copyDecision(d), result, nil
+}
+
+// closeDecisionLocked 任务被取消或退役时移除其尚未送达的决策请求并记录（调用方需持有 g.mu）
+func (g *PlanGenerator) closeDecisionLocked(task *TaskPlan, reason string) {
+	d := task.Decision
+	if d == nil {
+		return
+	}
+	task.Decision = nil
+
+	if g.memory != nil {
+		g.memory.Write("decision_closed", task.ID,
+			fmt.Sprintf("Decision closed (%s): %s", reason, d.Question), d)
+	}
+}
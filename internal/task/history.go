@@ -0,0 +1,223 @@
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TaskRun 任务的一次执行记录
+type TaskRun struct {
+	RunID            string    `json:"run_id"`
+	TaskID           string    `json:"task_id"`
+	StartedAt        time.Time `json:"started_at"`
+	EndedAt          time.Time `json:"ended_at"`
+	DurationMs       int64     `json:"duration_ms"`
+	Status           string    `json:"status"`
+	Result           string    `json:"result,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	Model            string    `json:"model,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens int       `json:"completion_tokens,omitempty"`
+}
+
+// ExecResult 执行器返回的输出及模型用量
+type ExecResult struct {
+	Output           string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// RunQuery 执行历史查询条件，零值字段不过滤
+type RunQuery struct {
+	Since  time.Time
+	Until  time.Time
+	Offset int
+	Limit  int
+}
+
+// RunHistory 按任务保存有上限的执行历史（每个任务一个 JSONL 文件）
+type RunHistory struct {
+	dir     string
+	maxRuns int
+	counts  map[string]int
+	mu      sync.Mutex
+}
+
+// NewRunHistory 创建执行历史存储
+func NewRunHistory(dir string) (*RunHistory, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create runs directory: %w", err)
+	}
+
+	return &RunHistory{
+		dir:     dir,
+		maxRuns: 200,
+		counts:  make(map[string]int),
+	}, nil
+}
+
+// SetMaxRuns 设置每个任务保留的最大执行记录数
+func (h *RunHistory) SetMaxRuns(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxRuns = n
+}
+
+// path 任务历史文件路径（任务 ID 转义后作为文件名）
+func (h *RunHistory) path(taskID string) string {
+	return filepath.Join(h.dir, url.QueryEscape(taskID)+".jsonl")
+}
+
+// Append 追加一条执行记录，超过上限时丢弃最早的记录
+func (h *RunHistory) Append(run TaskRun) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	path := h.path(run.TaskID)
+	count, known := h.counts[run.TaskID]
+	if !known {
+		runs, err := h.readLocked(run.TaskID)
+		if err != nil {
+			return err
+		}
+		count = len(runs)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open run history: %w", err)
+	}
+	if err := json.NewEncoder(file).Encode(run); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close run history: %w", err)
+	}
+	count++
+
+	if count > h.maxRuns {
+		runs, err := h.readLocked(run.TaskID)
+		if err != nil {
+			return err
+		}
+		runs = runs[len(runs)-h.maxRuns:]
+		if err := h.writeLocked(run.TaskID, runs); err != nil {
+			return err
+		}
+		count = len(runs)
+	}
+
+	h.counts[run.TaskID] = count
+	return nil
+}
+
+// List 按时间倒序返回任务的执行记录及满足条件的总数
+func (h *RunHistory) List(taskID string, q RunQuery) ([]TaskRun, int, error) {
+	h.mu.Lock()
+	runs, err := h.readLocked(taskID)
+	h.mu.Unlock()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matched := make([]TaskRun, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if !q.Since.IsZero() && run.StartedAt.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && run.StartedAt.After(q.Until) {
+			continue
+		}
+		matched = append(matched, run)
+	}
+
+	total := len(matched)
+	if q.Offset >= total {
+		return []TaskRun{}, total, nil
+	}
+	matched = matched[q.Offset:]
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	return matched, total, nil
+}
+
+// Latest 返回任务最近一次执行记录
+func (h *RunHistory) Latest(taskID string) (*TaskRun, error) {
+	runs, _, err := h.List(taskID, RunQuery{Limit: 1})
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// Remove 删除任务的全部执行历史
+func (h *RunHistory) Remove(taskID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.counts, taskID)
+	if err := os.Remove(h.path(taskID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove run history: %w", err)
+	}
+	return nil
+}
+
+// readLocked 读取任务的全部执行记录（按时间正序）
+func (h *RunHistory) readLocked(taskID string) ([]TaskRun, error) {
+	file, err := os.Open(h.path(taskID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run history: %w", err)
+	}
+	defer file.Close()
+
+	var runs []TaskRun
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var run TaskRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, scanner.Err()
+}
+
+// writeLocked 以临时文件加重命名的方式重写任务历史
+func (h *RunHistory) writeLocked(taskID string, runs []TaskRun) error {
+	path := h.path(taskID)
+	tmp := path + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create run history: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+	for _, run := range runs {
+		if err := encoder.Encode(run); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("failed to encode run: %w", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to close run history: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,222 @@
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// journalRecord 任务变更日志中的一条记录
+//
+// 每次任务变化都会写入完整的任务快照（put）或删除（delete），
+// 因此重放是幂等的：在旧的或新的快照文件上重放都能得到相同结果。
+type journalRecord struct {
+	Seq    int64     `json:"seq"`
+	Op     string    `json:"op"` // put | delete
+	TaskID string    `json:"task_id"`
+	Task   *TaskPlan `json:"task,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// RecoveryReport 启动时加载任务的恢复报告
+type RecoveryReport struct {
+	LoadedAt         time.Time `json:"loaded_at"`
+	SnapshotTasks    int       `json:"snapshot_tasks"`
+	JournalReplayed  int       `json:"journal_replayed"`
+	JournalCorrupt   int       `json:"journal_corrupt"`
+	JournalTornTail  bool      `json:"journal_torn_tail"`
+	SnapshotProblems []string  `json:"snapshot_problems,omitempty"`
+}
+
+// taskJournal 仅追加的任务变更日志（write-ahead journal）
+//
+// 每行格式为 "<crc32 十六进制> <JSON 记录>"，校验失败的行在重放时被跳过。
+type taskJournal struct {
+	file *os.File
+	seq  int64
+}
+
+// openJournal 打开（或创建）变更日志用于追加
+func openJournal(path string, seq int64) (*taskJournal, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open task journal: %w", err)
+	}
+	return &taskJournal{file: file, seq: seq}, nil
+}
+
+// append 写入一条记录并刷盘
+func (j *taskJournal) append(op string, taskID string, task *TaskPlan) error {
+	j.seq++
+	rec := journalRecord{
+		Seq:    j.seq,
+		Op:     op,
+		TaskID: taskID,
+		Task:   task,
+		Time:   time.Now(),
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+	line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(data), data)
+	if _, err := j.file.WriteString(line); err != nil {
+		return fmt.Errorf("failed to write task journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+// reset 快照落盘后清空日志
+func (j *taskJournal) reset() error {
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate task journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+// replayJournal 按顺序读取日志中校验通过的记录
+func replayJournal(path string, report *RecoveryReport, apply func(rec journalRecord)) (int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open task journal: %w", err)
+	}
+	defer file.Close()
+
+	var lastSeq int64
+	var pendingCorrupt int
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		rec, ok := decodeJournalLine(line)
+		if !ok {
+			pendingCorrupt++
+			continue
+		}
+		// 坏行之后仍有有效记录，说明坏行位于日志中间
+		report.JournalCorrupt += pendingCorrupt
+		pendingCorrupt = 0
+
+		apply(rec)
+		report.JournalReplayed++
+		if rec.Seq > lastSeq {
+			lastSeq = rec.Seq
+		}
+	}
+	// 末尾的坏行通常是写入过程中崩溃留下的半条记录
+	if pendingCorrupt > 0 {
+		report.JournalTornTail = true
+		report.JournalCorrupt += pendingCorrupt
+	}
+
+	return lastSeq, scanner.Err()
+}
+
+// decodeJournalLine 校验并解析一行日志
+func decodeJournalLine(line string) (journalRecord, bool) {
+	var rec journalRecord
+
+	sum, data, found := strings.Cut(line, " ")
+	if !found {
+		return rec, false
+	}
+	want, err := strconv.ParseUint(sum, 16, 32)
+	if err != nil || crc32.ChecksumIEEE([]byte(data)) != uint32(want) {
+		return rec, false
+	}
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return rec, false
+	}
+	if rec.Op == "put" && rec.Task == nil {
+		return rec, false
+	}
+	return rec, true
+}
+
+// writeFileAtomic 写入临时文件并刷盘后重命名，避免崩溃时留下写了一半的文件
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to chmod temp file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	// 目录刷盘让重命名本身持久化（部分平台不支持，忽略错误）
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// journalPut 持久化任务的最新状态（调用方需持有 g.mu）
+func (g *PlanGenerator) journalPut(task *TaskPlan) {
+	if g.store == nil {
+		return
+	}
+	if err := g.store.Put(copyPlan(task)); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// journalDelete 持久化任务删除（调用方需持有 g.mu）
+func (g *PlanGenerator) journalDelete(id string) {
+	if g.store == nil {
+		return
+	}
+	if err := g.store.Delete(id); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// GetRecoveryReport 返回最近一次 LoadTasks 的恢复报告
+func (g *PlanGenerator) GetRecoveryReport() *RecoveryReport {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.recovery == nil {
+		return nil
+	}
+	report := *g.recovery
+	return &report
+}
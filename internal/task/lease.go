@@ -0,0 +1,240 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// 执行被中断的原因
+const (
+	stopCancelled = "cancelled" // 被大脑取消
+	stopTimeout   = "timeout"   // 超过单次执行时长上限
+)
+
+// RunLease 正在执行的任务的租约
+//
+// 执行期间由心跳定期续约并持久化；重启后或租约过期仍处于 running 的任务
+// 视为执行已失联，会被重新排队。
+type RunLease struct {
+	Owner       string    `json:"owner"`
+	StartedAt   time.Time `json:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// runHandle 进程内正在执行的任务
+type runHandle struct {
+	cancel context.CancelFunc
+	reason string
+}
+
+// newInstanceID 生成本进程的租约持有者标识
+func newInstanceID() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+// SetLeaseTTL 设置执行租约的有效期，心跳间隔为其三分之一
+func (g *PlanGenerator) SetLeaseTTL(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.leaseTTL = d
+}
+
+// SetRunTimeout 设置单次执行的时长上限（0 表示不限）
+func (g *PlanGenerator) SetRunTimeout(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.runTimeout = d
+}
+
+// acquireLease 为即将执行的任务登记租约（调用方需持有 g.mu）
+func (g *PlanGenerator) acquireLease(task *TaskPlan, now time.Time) {
+	task.Lease = &RunLease{
+		Owner:       g.instanceID,
+		StartedAt:   now,
+		HeartbeatAt: now,
+		ExpiresAt:   now.Add(g.leaseTTL),
+	}
+}
+
+// beginRun 登记正在执行的任务并启动心跳，返回可被 CancelTask 取消的 context
+func (g *PlanGenerator) beginRun(id string, started time.Time) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	g.runningMu.Lock()
+	g.running[id] = &runHandle{cancel: cancel}
+	g.runningMu.Unlock()
+
+	g.mu.Lock()
+	ttl, timeout := g.leaseTTL, g.runTimeout
+	g.mu.Unlock()
+
+	go g.heartbeat(ctx, id, started, ttl, timeout)
+	return ctx
+}
+
+// endRun 注销正在执行的任务，返回执行被中断的原因（正常结束为空）
+func (g *PlanGenerator) endRun(id string, ctx context.Context) string {
+	stopped := ctx.Err() != nil
+
+	g.runningMu.Lock()
+	handle, ok := g.running[id]
+	if ok {
+		handle.cancel()
+		delete(g.running, id)
+	}
+	g.runningMu.Unlock()
+
+	if !stopped {
+		return ""
+	}
+	if ok && handle.reason != "" {
+		return handle.reason
+	}
+	return stopCancelled
+}
+
+// cancelRun 中断进程内正在执行的任务，任务未在执行时返回 false
+func (g *PlanGenerator) cancelRun(id, reason string) bool {
+	g.runningMu.Lock()
+	defer g.runningMu.Unlock()
+
+	handle, ok := g.running[id]
+	if !ok {
+		return false
+	}
+	if handle.reason == "" {
+		handle.reason = reason
+	}
+	handle.cancel()
+	return true
+}
+
+// isRunning 判断任务是否在本进程内执行
+func (g *PlanGenerator) isRunning(id string) bool {
+	g.runningMu.Lock()
+	defer g.runningMu.Unlock()
+	_, ok := g.running[id]
+	return ok
+}
+
+// heartbeat 定期续约并持久化，执行超过时长上限时中断执行
+func (g *PlanGenerator) heartbeat(ctx context.Context, id string, started time.Time, ttl, timeout time.Duration) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout - time.Since(started))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline:
+			g.cancelRun(id, stopTimeout)
+			return
+		case now := <-ticker.C:
+			g.mu.Lock()
+			if task := g.findTask(id); task != nil && task.Status == "running" && task.Lease != nil {
+				task.Lease.HeartbeatAt = now
+				task.Lease.ExpiresAt = now.Add(ttl)
+				g.journalPut(task)
+			}
+			g.mu.Unlock()
+		}
+	}
+}
+
+// recoverStaleTasks 重新排队执行已失联的任务（调用方需持有 g.mu）
+//
+// 状态为 running 却不在本进程执行的任务，若租约属于其他进程（如重启前的进程）
+// 或已过期，说明执行方已不存在。
+func (g *PlanGenerator) recoverStaleTasks(now time.Time) {
+	for _, tasks := range []map[string]*TaskPlan{g.periodicTasks, g.onceTasks} {
+		for _, task := range tasks {
+			if task.Status != "running" || g.isRunning(task.ID) {
+				continue
+			}
+			lease := task.Lease
+			if lease != nil && lease.Owner == g.instanceID && now.Before(lease.ExpiresAt) {
+				continue
+			}
+
+			reason := "no lease"
+			if lease != nil && lease.Owner != g.instanceID {
+				reason = fmt.Sprintf("lease held by previous process %s", lease.Owner)
+			} else if lease != nil {
+				reason = fmt.Sprintf("lease expired at %s", lease.ExpiresAt.Format(time.RFC3339))
+			}
+			task.NextRun = now
+			g.requeueLocked(task, now, reason)
+		}
+	}
+}
+
+// requeueLocked 将中断的执行重新排队并记录 recovered 变化（调用方需持有 g.mu）
+func (g *PlanGenerator) requeueLocked(task *TaskPlan, now time.Time, reason string) {
+	oldStatus := task.Status
+	task.Status = "pending"
+	task.Error = reason
+	task.Lease = nil
+
+	g.appendChange(TaskChange{
+		Type:      ChangeTypeRecovered,
+		TaskID:    task.ID,
+		Timestamp: now,
+		OldStatus: oldStatus,
+		NewStatus: task.Status,
+		Reason:    reason,
+	})
+
+	if g.memory != nil {
+		g.memory.Write("task_recovered", task.ID,
+			fmt.Sprintf("Task requeued: %s", reason),
+			map[string]string{"reason": reason})
+	}
+	g.journalPut(task)
+}
+
+// RunningTask 正在执行的任务及其租约
+type RunningTask struct {
+	*TaskPlan
+	ElapsedMs int64 `json:"elapsed_ms"`
+}
+
+// GetRunningTasks 返回所有正在执行的任务
+func (g *PlanGenerator) GetRunningTasks() []RunningTask {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	var running []RunningTask
+	for _, tasks := range []map[string]*TaskPlan{g.periodicTasks, g.onceTasks} {
+		for _, task := range tasks {
+			if task.Status != "running" {
+				continue
+			}
+			rt := RunningTask{TaskPlan: copyPlan(task)}
+			if task.Lease != nil {
+				rt.ElapsedMs = now.Sub(task.Lease.StartedAt).Milliseconds()
+			}
+			running = append(running, rt)
+		}
+	}
+	return running
+}
+
+// timeoutReason 超时中断的说明
+func (g *PlanGenerator) timeoutReason() string {
+	return fmt.Sprintf("run exceeded timeout of %s", g.runTimeout)
+}
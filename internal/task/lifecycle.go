@@ -0,0 +1,104 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+// Validate 检查大脑提交的任务参数
+func (t BrainTask) Validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if t.Type != TaskTypePeriodic && t.Type != TaskTypeOnce {
+		return fmt.Errorf("unknown task type %q", t.Type)
+	}
+	if t.Interval != "" {
+		if _, err := time.ParseDuration(t.Interval); err != nil {
+			return fmt.Errorf("invalid interval %q", t.Interval)
+		}
+	}
+	if err := ValidateConditions(t.Conditions); err != nil {
+		return err
+	}
+	if err := ValidateMetrics(t.Metrics); err != nil {
+		return err
+	}
+	if t.MaxRuns < 0 {
+		return fmt.Errorf("max_runs cannot be negative")
+	}
+	if t.Retention != "" {
+		if _, err := time.ParseDuration(t.Retention); err != nil {
+			return fmt.Errorf("invalid retention %q", t.Retention)
+		}
+	}
+	return nil
+}
+
+// SetRetention 设置一次性任务结束后的默认保留时长（0 表示一直保留）
+func (g *PlanGenerator) SetRetention(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.retention = d
+}
+
+// retireTasks 退役过期、达到执行次数上限或超过保留期的任务（调用方需持有 g.mu）
+func (g *PlanGenerator) retireTasks(now time.Time) {
+	for _, tasks := range []map[string]*TaskPlan{g.periodicTasks, g.onceTasks} {
+		for _, task := range tasks {
+			if reason := g.retireReason(task, now); reason != "" {
+				g.retireLocked(task, reason)
+			}
+		}
+	}
+}
+
+// retireReason 返回任务需要退役的原因，不需要退役时返回空字符串
+func (g *PlanGenerator) retireReason(task *TaskPlan, now time.Time) string {
+	if task.Status == "running" {
+		return ""
+	}
+	if task.ExpiresAt != nil && !now.Before(*task.ExpiresAt) {
+		return fmt.Sprintf("expired at %s", task.ExpiresAt.Format(time.RFC3339))
+	}
+	if task.Type == TaskTypePeriodic && task.MaxRuns > 0 && task.ExecCount >= task.MaxRuns {
+		return fmt.Sprintf("reached max runs (%d)", task.MaxRuns)
+	}
+	if task.Type == TaskTypeOnce && (task.Status == "completed" || task.Status == "cancelled") {
+		retention := g.retention
+		if task.Retention != "" {
+			if d, err := time.ParseDuration(task.Retention); err == nil {
+				retention = d
+			}
+		}
+		finished := task.FinishedAt
+		if finished.IsZero() {
+			finished = task.LastRun
+		}
+		if finished.IsZero() {
+			finished = task.CreatedAt
+		}
+		if retention > 0 && now.Sub(finished) >= retention {
+			return fmt.Sprintf("retention of %s elapsed", retention)
+		}
+	}
+	return ""
+}
+
+// retireLocked 移除任务并记录退役原因，执行历史保留，由每个任务的记录上限裁剪
+func (g *PlanGenerator) retireLocked(task *TaskPlan, reason string) {
+	oldStatus := task.Status
+	g.closeDecisionLocked(task, "task retired")
+	g.dropLocked(task)
+	g.recordChange(ChangeTypeRetired, task.ID, oldStatus, "retired")
+
+	if g.memory != nil {
+		g.memory.Write("task_retired", task.ID,
+			fmt.Sprintf("Task retired: %s", reason),
+			map[string]interface{}{
+				"reason":     reason,
+				"command":    task.Command,
+				"exec_count": task.ExecCount,
+			})
+	}
+}
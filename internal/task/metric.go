@@ -0,0 +1,85 @@
+package task
+
+import (
+	"fmt"
+
+	"cerebellum/internal/memory"
+)
+
+// MetricSpec 从任务结果中提取的数值指标，每次成功执行后写入一条 metric 记忆
+type MetricSpec struct {
+	Name    string            `json:"name"`
+	Unit    string            `json:"unit,omitempty"`
+	Pattern string            `json:"pattern,omitempty"` // 可选，首个分组为数值；为空时取结果中的第一个数
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// Validate 检查指标配置是否有效
+func (m MetricSpec) Validate() error {
+	if err := memory.ValidateMetricName(m.Name); err != nil {
+		return err
+	}
+	if m.Pattern != "" {
+		if _, err := compilePattern(m.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	for key := range m.Labels {
+		if key == "" {
+			return fmt.Errorf("empty label name")
+		}
+	}
+	return nil
+}
+
+// ValidateMetrics 检查一组指标，名称不能重复
+func ValidateMetrics(specs []MetricSpec) error {
+	names := make(map[string]bool, len(specs))
+	for i, m := range specs {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("metric %d: %w", i, err)
+		}
+		if names[m.Name] {
+			return fmt.Errorf("metric %d: duplicate name %q", i, m.Name)
+		}
+		names[m.Name] = true
+	}
+	return nil
+}
+
+// copyMetrics 复制指标配置，避免共享标签
+func copyMetrics(specs []MetricSpec) []MetricSpec {
+	if specs == nil {
+		return nil
+	}
+	out := make([]MetricSpec, len(specs))
+	for i, m := range specs {
+		out[i] = m
+		if m.Labels != nil {
+			out[i].Labels = make(map[string]string, len(m.Labels))
+			for k, v := range m.Labels {
+				out[i].Labels[k] = v
+			}
+		}
+	}
+	return out
+}
+
+// recordMetricsLocked 从执行结果中提取任务的指标并写入记忆，提取不到的指标跳过（调用方需持有 g.mu）
+func (g *PlanGenerator) recordMetricsLocked(task *TaskPlan, result string) {
+	if g.memory == nil {
+		return
+	}
+	for _, spec := range task.Metrics {
+		value, ok := extractNumber(spec.Pattern, result)
+		if !ok {
+			continue
+		}
+		g.memory.WriteMetric(task.ID, memory.Metric{
+			Name:   spec.Name,
+			Value:  value,
+			Unit:   spec.Unit,
+			Labels: spec.Labels,
+		})
+	}
+}
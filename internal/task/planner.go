@@ -1,11 +1,14 @@
 package task
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,21 +30,54 @@ type BrainTask struct {
 	Interval string            `json:"interval,omitempty"`
 	Command  string            `json:"command"`
 	Metadata map[string]string `json:"metadata,omitempty"`
+	// Conditions 周期任务的告警条件，为空时每次执行都按原方式上报
+	Conditions []AlertCondition `json:"conditions,omitempty"`
+	// Metrics 从每次执行结果中提取的数值指标，写入 metric 记忆
+	Metrics []MetricSpec `json:"metrics,omitempty"`
+	// ExpiresAt 到期后任务自动退役
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// MaxRuns 周期任务执行达到该次数后自动退役（0 表示不限）
+	MaxRuns int `json:"max_runs,omitempty"`
+	// Retention 一次性任务结束后保留的时长，如 "24h"（空则使用全局默认值）
+	Retention string `json:"retention,omitempty"`
+	// Priority 优先级，数值越大越先执行（默认 0）
+	Priority int `json:"priority,omitempty"`
+	// Owner 任务归属（如提交任务的大脑或业务方），同一 owner 的任务共享并发配额
+	Owner string `json:"owner,omitempty"`
+	// IfMatch 乐观并发控制：仅当现有任务版本等于该值时才更新（新任务为 0）
+	IfMatch *int `json:"if_match,omitempty"`
 }
 
 // TaskPlan 小脑生成的任务计划
 type TaskPlan struct {
 	ID        string    `json:"id"`
 	Type      TaskType  `json:"type"`
+	Version   int       `json:"version"`
 	Command   string    `json:"command"`
 	Interval  string    `json:"interval"` // 周期任务的间隔（不能省略）
 	CreatedAt time.Time `json:"created_at"`
 	NextRun   time.Time `json:"next_run,omitempty"`
 	LastRun   time.Time `json:"last_run,omitempty"`
-	ExecCount int       `json:"exec_count"`
-	Status    string    `json:"status"`
-	Result    string    `json:"result,omitempty"`
-	Error     string    `json:"error,omitempty"`
+	// FinishedAt 最近一次执行结束或被取消的时间，一次性任务的保留期从此开始计算
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	ExecCount  int       `json:"exec_count"`
+	Status     string    `json:"status"`
+	Paused     bool      `json:"paused,omitempty"`
+	Result     string    `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+
+	Conditions []AlertCondition `json:"conditions,omitempty"`
+	Metrics    []MetricSpec     `json:"metrics,omitempty"`
+	ExpiresAt  *time.Time       `json:"expires_at,omitempty"`
+	MaxRuns    int              `json:"max_runs,omitempty"`
+	Retention  string           `json:"retention,omitempty"`
+	Priority   int              `json:"priority,omitempty"`
+	Owner      string           `json:"owner,omitempty"`
+
+	// Lease 执行中的租约，仅在 running 时存在
+	Lease *RunLease `json:"lease,omitempty"`
+	// Decision 向大脑提出的决策请求，回答后在下一次执行中读取
+	Decision *Decision `json:"decision,omitempty"`
 }
 
 // TaskResult 完成的任务结果
@@ -59,6 +95,14 @@ const (
 	ChangeTypeCompleted ChangeType = "completed"
 	ChangeTypeFailed    ChangeType = "failed"
 	ChangeTypeUpdated   ChangeType = "updated"
+	ChangeTypePaused    ChangeType = "paused"
+	ChangeTypeResumed   ChangeType = "resumed"
+	ChangeTypeCancelled ChangeType = "cancelled"
+	ChangeTypeRemoved   ChangeType = "removed"
+	ChangeTypeAlert     ChangeType = "alert"
+	ChangeTypeRetired   ChangeType = "retired"
+	ChangeTypeRecovered ChangeType = "recovered"
+	ChangeTypeDecision  ChangeType = "decision"
 )
 
 // TaskChange 任务变化
@@ -69,6 +113,7 @@ type TaskChange struct {
 	OldStatus string     `json:"old_status,omitempty"`
 	NewStatus string     `json:"new_status,omitempty"`
 	Result    string     `json:"result,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
 }
 
 // PlanGenerator 生成任务计划（增强版）
@@ -77,13 +122,27 @@ type PlanGenerator struct {
 	onceTasks     map[string]*TaskPlan
 	changes       []TaskChange
 	changesMu     sync.Mutex
+	onChange      func(TaskChange)
 	taskCount     int
 	lastTaskCount int
 	memory        *memory.JSONLMemory
-	dataDir       string
+	store         Store
 	mu            sync.Mutex
+	running       map[string]*runHandle
+	runningMu     sync.Mutex
+	judge         Judge
+	retention     time.Duration
+	recovery      *RecoveryReport
+	instanceID    string
+	leaseTTL      time.Duration
+	runTimeout    time.Duration
+	limits        SchedulerLimits
+	maxTasks      int
 }
 
+// Executor 执行任务命令，ctx 在任务被取消时结束
+type Executor func(ctx context.Context, command string) (ExecResult, error)
+
 // NewPlanGenerator 创建计划生成器
 func NewPlanGenerator(mem *memory.JSONLMemory) *PlanGenerator {
 	return &PlanGenerator{
@@ -91,70 +150,259 @@ func NewPlanGenerator(mem *memory.JSONLMemory) *PlanGenerator {
 		onceTasks:     make(map[string]*TaskPlan),
 		changes:       make([]TaskChange, 0),
 		memory:        mem,
+		running:       make(map[string]*runHandle),
+		instanceID:    newInstanceID(),
+		leaseTTL:      time.Minute,
+		limits:        SchedulerLimits{MaxConcurrency: 4, OwnerConcurrency: 2},
 	}
 }
 
-// GeneratePlan 从大脑任务生成计划
-func (g *PlanGenerator) GeneratePlan(tasks []BrainTask) int {
+// UpsertResult 提交任务后的处理结果
+type UpsertResult struct {
+	Created   []string          `json:"created"`
+	Updated   []string          `json:"updated"`
+	Unchanged []string          `json:"unchanged"`
+	Conflicts []VersionConflict `json:"conflicts,omitempty"`
+	Running   []string          `json:"running,omitempty"` // 正在执行、不能改变类型的任务
+	Quota     *QuotaExceeded    `json:"quota_exceeded,omitempty"`
+}
+
+// QuotaExceeded 新建任务会超出任务数上限
+type QuotaExceeded struct {
+	Limit     int `json:"limit"`
+	Current   int `json:"current"`
+	Requested int `json:"requested"`
+}
+
+// VersionConflict if_match 与当前版本不一致
+type VersionConflict struct {
+	ID       string `json:"id"`
+	Expected int    `json:"expected"`
+	Current  int    `json:"current"`
+}
+
+// GeneratePlan 从大脑任务生成计划：新任务创建，已有任务按内容更新（版本号递增），
+// 内容相同的任务保持不变。只要有一个任务的 if_match 不满足、要改变正在执行的任务的类型，
+// 或超出任务数上限，整批都不会生效。
+func (g *PlanGenerator) GeneratePlan(tasks []BrainTask) *UpsertResult {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	return g.generatePlanLocked(tasks)
+}
+
+// generatePlanLocked 同 GeneratePlan（调用方需持有 g.mu）
+func (g *PlanGenerator) generatePlanLocked(tasks []BrainTask) *UpsertResult {
+	result := &UpsertResult{
+		Created:   make([]string, 0),
+		Updated:   make([]string, 0),
+		Unchanged: make([]string, 0),
+	}
+
+	for _, task := range tasks {
+		existing := g.findTask(task.ID)
+		// 类型变化会替换任务，与删除一样需要等正在进行的执行结束
+		if existing != nil && existing.Type != task.Type && existing.Status == "running" {
+			result.Running = append(result.Running, task.ID)
+		}
+		if task.IfMatch == nil {
+			continue
+		}
+		current := 0
+		if existing != nil {
+			current = existing.Version
+		}
+		if *task.IfMatch != current {
+			result.Conflicts = append(result.Conflicts, VersionConflict{
+				ID:       task.ID,
+				Expected: *task.IfMatch,
+				Current:  current,
+			})
+		}
+	}
+	if len(result.Conflicts) > 0 || len(result.Running) > 0 {
+		return result
+	}
+
+	if g.maxTasks > 0 {
+		created := make(map[string]bool)
+		for _, task := range tasks {
+			if g.findTask(task.ID) == nil {
+				created[task.ID] = true
+			}
+		}
+		current := len(g.periodicTasks) + len(g.onceTasks)
+		if current+len(created) > g.maxTasks {
+			result.Quota = &QuotaExceeded{Limit: g.maxTasks, Current: current, Requested: len(created)}
+			return result
+		}
+	}
 
 	g.lastTaskCount = g.taskCount
-	newTaskCount := 0
 
 	for _, task := range tasks {
-		if task.Type == TaskTypePeriodic {
-			if _, exists := g.periodicTasks[task.ID]; !exists {
-				// 确保 Interval 有默认值
-				interval := task.Interval
-				if interval == "" {
-					interval = "30s" // 默认间隔 30 秒
-					log.Printf("WARNING: Task %s has empty interval, using default 30s", task.ID)
-				}
-				nextRun := g.calcNextRun(interval, time.Now())
-				g.periodicTasks[task.ID] = &TaskPlan{
-					ID:        task.ID,
-					Type:      TaskTypePeriodic,
-					Command:   task.Command,
-					Interval:  interval,
-					CreatedAt: time.Now(),
-					NextRun:   nextRun,
-					Status:    "pending",
-				}
-				g.recordChange(ChangeTypeAdded, task.ID, "", "pending")
-				newTaskCount++
+		if task.Type != TaskTypePeriodic && task.Type != TaskTypeOnce {
+			continue
+		}
 
-				// 写入记忆
-				if g.memory != nil {
+		desired := g.planFromBrainTask(task)
+		existing := g.findTask(task.ID)
+
+		if existing == nil {
+			g.addPlanLocked(desired)
+			result.Created = append(result.Created, task.ID)
+
+			// 写入记忆
+			if g.memory != nil {
+				if task.Type == TaskTypePeriodic {
 					g.memory.Write("task_assigned", task.ID,
-						fmt.Sprintf("New periodic task assigned: %s (interval: %s)", task.Command, interval),
+						fmt.Sprintf("New periodic task assigned: %s (interval: %s)", task.Command, desired.Interval),
 						task)
-				}
-			}
-		} else if task.Type == TaskTypeOnce {
-			if _, exists := g.onceTasks[task.ID]; !exists {
-				g.onceTasks[task.ID] = &TaskPlan{
-					ID:        task.ID,
-					Type:      TaskTypeOnce,
-					Command:   task.Command,
-					CreatedAt: time.Now(),
-					NextRun:   time.Now(),
-					Status:    "pending",
-				}
-				g.recordChange(ChangeTypeAdded, task.ID, "", "pending")
-				newTaskCount++
-
-				if g.memory != nil {
+				} else {
 					g.memory.Write("task_assigned", task.ID,
 						fmt.Sprintf("New one-time task assigned: %s", task.Command),
 						task)
 				}
 			}
+			continue
+		}
+
+		if existing.Type == desired.Type && sameSpec(existing, desired) {
+			result.Unchanged = append(result.Unchanged, task.ID)
+			continue
+		}
+
+		oldStatus := existing.Status
+		if existing.Type != desired.Type {
+			// 类型变化视为替换原任务：直接移到另一张任务表，保留执行历史
+			desired.Version = existing.Version + 1
+			desired.CreatedAt = existing.CreatedAt
+			g.movePlanLocked(existing, desired)
+		} else {
+			g.applySpec(existing, desired)
+		}
+		result.Updated = append(result.Updated, task.ID)
+
+		plan := g.findTask(task.ID)
+		g.recordChange(ChangeTypeUpdated, task.ID, oldStatus, plan.Status)
+		if g.memory != nil {
+			g.memory.Write("task_updated", task.ID,
+				fmt.Sprintf("Task updated to version %d: %s", plan.Version, plan.Command),
+				task)
 		}
 	}
 
 	g.taskCount = len(g.periodicTasks) + len(g.onceTasks)
-	return newTaskCount
+	return result
+}
+
+// planFromBrainTask 根据大脑任务构造新的任务计划
+func (g *PlanGenerator) planFromBrainTask(task BrainTask) *TaskPlan {
+	now := time.Now()
+	plan := &TaskPlan{
+		ID:        task.ID,
+		Type:      task.Type,
+		Version:   1,
+		Command:   task.Command,
+		CreatedAt: now,
+		NextRun:   now,
+		Status:    "pending",
+
+		Metrics:   copyMetrics(task.Metrics),
+		ExpiresAt: task.ExpiresAt,
+		Priority:  task.Priority,
+		Owner:     task.Owner,
+	}
+
+	if task.Type == TaskTypePeriodic {
+		// 确保 Interval 有默认值
+		interval := task.Interval
+		if interval == "" {
+			interval = "30s" // 默认间隔 30 秒
+			log.Printf("WARNING: Task %s has empty interval, using default 30s", task.ID)
+		}
+		plan.Interval = interval
+		plan.NextRun = g.calcNextRun(interval, now)
+		plan.Conditions = task.Conditions
+		plan.MaxRuns = task.MaxRuns
+	} else {
+		plan.Retention = task.Retention
+	}
+	return plan
+}
+
+// addPlanLocked 将任务计划加入对应的任务表（调用方需持有 g.mu）
+func (g *PlanGenerator) addPlanLocked(plan *TaskPlan) {
+	if plan.Type == TaskTypePeriodic {
+		g.periodicTasks[plan.ID] = plan
+	} else {
+		g.onceTasks[plan.ID] = plan
+	}
+	g.taskCount = len(g.periodicTasks) + len(g.onceTasks)
+	g.recordChange(ChangeTypeAdded, plan.ID, "", plan.Status)
+	g.journalPut(plan)
+}
+
+// movePlanLocked 用类型不同的新计划替换原任务，不删除执行历史，原任务的决策请求随之关闭（调用方需持有 g.mu）
+func (g *PlanGenerator) movePlanLocked(old, plan *TaskPlan) {
+	g.closeDecisionLocked(old, "task type changed")
+	delete(g.periodicTasks, old.ID)
+	delete(g.onceTasks, old.ID)
+	if plan.Type == TaskTypePeriodic {
+		g.periodicTasks[plan.ID] = plan
+	} else {
+		g.onceTasks[plan.ID] = plan
+	}
+	g.taskCount = len(g.periodicTasks) + len(g.onceTasks)
+	g.journalPut(plan)
+}
+
+// applySpec 将新内容应用到已有任务并递增版本（调用方需持有 g.mu）
+func (g *PlanGenerator) applySpec(plan, desired *TaskPlan) {
+	commandChanged := plan.Command != desired.Command
+
+	plan.Command = desired.Command
+	plan.Conditions = desired.Conditions
+	plan.Metrics = desired.Metrics
+	plan.ExpiresAt = desired.ExpiresAt
+	plan.MaxRuns = desired.MaxRuns
+	plan.Retention = desired.Retention
+	plan.Priority = desired.Priority
+	plan.Owner = desired.Owner
+	plan.Version++
+
+	if plan.Type == TaskTypePeriodic && plan.Interval != desired.Interval {
+		plan.Interval = desired.Interval
+		plan.NextRun = g.calcNextRun(plan.Interval, time.Now())
+	}
+
+	// 已结束的一次性任务换了命令，说明大脑需要重新执行
+	if plan.Type == TaskTypeOnce && commandChanged && plan.Status != "running" {
+		plan.Status = "pending"
+		plan.NextRun = time.Now()
+	}
+	g.journalPut(plan)
+}
+
+// sameSpec 比较大脑可设置的任务内容是否一致
+func sameSpec(a, b *TaskPlan) bool {
+	if a.Command != b.Command || a.Interval != b.Interval ||
+		a.MaxRuns != b.MaxRuns || a.Retention != b.Retention ||
+		a.Priority != b.Priority || a.Owner != b.Owner {
+		return false
+	}
+	if (a.ExpiresAt == nil) != (b.ExpiresAt == nil) ||
+		(a.ExpiresAt != nil && !a.ExpiresAt.Equal(*b.ExpiresAt)) {
+		return false
+	}
+	if len(a.Conditions) != len(b.Conditions) {
+		return false
+	}
+	for i := range a.Conditions {
+		if !reflect.DeepEqual(a.Conditions[i], b.Conditions[i]) {
+			return false
+		}
+	}
+	return reflect.DeepEqual(a.Metrics, b.Metrics)
 }
 
 // calcNextRun 计算下次执行时间
@@ -167,85 +415,267 @@ func (g *PlanGenerator) calcNextRun(interval string, from time.Time) time.Time {
 }
 
 // ExecuteTasks 执行待执行的任务
-func (g *PlanGenerator) ExecuteTasks(executor func(command string) (string, error)) {
+func (g *PlanGenerator) ExecuteTasks(executor Executor) {
 	now := time.Now()
 
-	// 执行一次性任务
-	for id, task := range g.onceTasks {
+	g.mu.Lock()
+	g.recoverStaleTasks(now)
+	g.retireTasks(now)
+	var due []*TaskPlan
+	for _, task := range g.onceTasks {
 		if task.Status == "pending" || task.Status == "failed" {
-			oldStatus := task.Status
-			task.Status = "running"
-			task.LastRun = now
-
-			result, err := executor(task.Command)
-			if err != nil {
-				task.Status = "failed"
-				task.Error = err.Error()
-				g.recordChange(ChangeTypeFailed, id, oldStatus, "failed")
-
-				if g.memory != nil {
-					g.memory.Write("task_failed", id,
-						fmt.Sprintf("Task failed: %v", err),
-						nil)
-				}
-			} else {
-				task.Status = "completed"
-				task.Result = result
-				task.ExecCount++
-				g.recordChange(ChangeTypeCompleted, id, oldStatus, "completed")
-
-				if g.memory != nil {
-					g.memory.Write("task_completed", id,
-						fmt.Sprintf("Task completed: %s", result),
-						nil)
-				}
+			due = append(due, task)
+		}
+	}
+	for _, task := range g.periodicTasks {
+		if task.Paused {
+			if task.Status != "running" {
+				task.Status = "paused"
 			}
+			continue
+		}
+		if now.Equal(task.NextRun) || now.After(task.NextRun) {
+			due = append(due, task)
+		} else {
+			task.Status = "pending"
 		}
 	}
+	ordered := scheduleOrder(due)
+	g.mu.Unlock()
 
-	// 执行周期任务
-	for id, task := range g.periodicTasks {
-		if now.Equal(task.NextRun) || now.After(task.NextRun) {
-			oldStatus := task.Status
-			task.Status = "running"
-			task.LastRun = now
-			task.ExecCount++
-
-			// 确保 Interval 有值
-			if task.Interval == "" {
-				log.Printf("WARNING: Task %s has empty Interval, using default 30s", id)
-				task.Interval = "30s"
-			}
+	// 按优先级和 owner 轮转并发执行
+	g.dispatch(ordered, now, executor)
 
-			result, err := executor(task.Command)
-			if err != nil {
-				task.Status = "failed"
-				task.Error = err.Error()
-			} else {
-				task.Status = "completed"
-				task.Result = result
-			}
+	// 达到执行次数上限的任务在本轮结束后立即退役
+	g.mu.Lock()
+	g.retireTasks(time.Now())
+	g.mu.Unlock()
+}
 
-			task.NextRun = g.calcNextRun(task.Interval, now)
-			g.recordChange(ChangeTypeUpdated, task.ID, oldStatus, task.Status)
+// runOnceTask 执行单个一次性任务
+func (g *PlanGenerator) runOnceTask(task *TaskPlan, now time.Time, executor Executor) {
+	g.mu.Lock()
+	id := task.ID
+	// 任务可能在等待期间被删除或取消
+	if g.onceTasks[id] != task || (task.Status != "pending" && task.Status != "failed") {
+		g.mu.Unlock()
+		return
+	}
+	oldStatus := task.Status
+	started := time.Now()
+	task.Status = "running"
+	task.LastRun = now
+	g.acquireLease(task, started)
+	command, delivered := task.Command, answeredDecision(task)
+	if delivered != nil {
+		command = withDecision(command, delivered)
+	}
+	g.journalPut(task)
+	g.mu.Unlock()
 
-			if g.memory != nil {
-				g.memory.Write("task_executed", task.ID,
-					fmt.Sprintf("Periodic task executed: %s", result),
-					nil)
-			}
+	ctx := g.beginRun(id, started)
+	out, err := executor(ctx, command)
+	stopped := g.endRun(id, ctx)
+	result := out.Output
+	g.recordRun(id, started, out, err, stopped)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	task.Lease = nil
+
+	if stopped == stopTimeout {
+		g.requeueLocked(task, time.Now(), g.timeoutReason())
+		return
+	}
+	defer g.journalPut(task)
+	task.FinishedAt = time.Now()
+
+	if stopped == stopCancelled {
+		task.Status = "cancelled"
+		task.Error = "cancelled by brain"
+		g.recordChange(ChangeTypeCancelled, id, oldStatus, "cancelled")
+
+		if g.memory != nil {
+			g.memory.Write("task_cancelled", id, "Task cancelled while running", nil)
+		}
+	} else if err != nil {
+		task.Status = "failed"
+		task.Error = err.Error()
+		g.recordChange(ChangeTypeFailed, id, oldStatus, "failed")
+
+		if g.memory != nil {
+			g.memory.Write("task_failed", id,
+				fmt.Sprintf("Task failed: %v", err),
+				nil)
+		}
+	} else {
+		task.Result = result
+		task.Error = ""
+		task.ExecCount++
+		g.deliverDecisionLocked(task, delivered)
+		g.recordMetricsLocked(task, result)
+
+		// 输出中请求了大脑决策时任务进入 waiting，回答后重新执行
+		if d := parseDecisionRequest(result); d != nil && g.raiseDecisionLocked(task, d, oldStatus) {
+			return
+		}
+		task.Status = "completed"
+		g.recordChange(ChangeTypeCompleted, id, oldStatus, "completed")
+
+		if g.memory != nil {
+			g.memory.Write("task_completed", id,
+				fmt.Sprintf("Task completed: %s", result),
+				nil)
+		}
+	}
+}
+
+// answeredDecision 返回任务已回答、待本次执行读取的决策（调用方需持有 g.mu）
+func answeredDecision(task *TaskPlan) *Decision {
+	if task.Decision != nil && task.Decision.Status == DecisionAnswered {
+		return task.Decision
+	}
+	return nil
+}
+
+// runPeriodicTask 执行单个周期任务
+func (g *PlanGenerator) runPeriodicTask(task *TaskPlan, now time.Time, executor Executor) {
+	g.mu.Lock()
+	id := task.ID
+	if g.periodicTasks[id] != task || task.Paused || task.Status == "running" {
+		g.mu.Unlock()
+		return
+	}
+	oldStatus := task.Status
+	prevResult := task.Result
+	conds := append([]AlertCondition(nil), task.Conditions...)
+	judge := g.judge
+	started := time.Now()
+	task.Status = "running"
+	task.LastRun = now
+	task.ExecCount++
+	g.acquireLease(task, started)
+
+	// 确保 Interval 有值
+	if task.Interval == "" {
+		log.Printf("WARNING: Task %s has empty Interval, using default 30s", id)
+		task.Interval = "30s"
+	}
+	command, delivered := task.Command, answeredDecision(task)
+	if delivered != nil {
+		command = withDecision(command, delivered)
+	}
+	g.journalPut(task)
+	g.mu.Unlock()
+
+	ctx := g.beginRun(id, started)
+	out, err := executor(ctx, command)
+	stopped := g.endRun(id, ctx)
+	cancelled := stopped == stopCancelled
+	result := out.Output
+	g.recordRun(id, started, out, err, stopped)
+
+	// 在释放锁的情况下评估告警条件（llm 条件可能较慢）
+	var reasons []string
+	if err == nil && stopped == "" && len(conds) > 0 {
+		judgeCtx, cancel := context.WithTimeout(context.Background(), judgeTimeout)
+		reasons = evaluateConditions(judgeCtx, conds, prevResult, result, judge)
+		cancel()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	task.Lease = nil
+
+	if stopped == stopTimeout {
+		// 超时的执行不计入次数，按周期重新排队
+		task.ExecCount--
+		task.NextRun = g.calcNextRun(task.Interval, now)
+		g.requeueLocked(task, time.Now(), g.timeoutReason())
+		return
+	}
+	defer g.journalPut(task)
+	task.FinishedAt = time.Now()
+
+	if cancelled {
+		// 周期任务取消的是本次执行，下个周期照常运行
+		task.Status = "cancelled"
+		task.Error = "cancelled by brain"
+	} else if err != nil {
+		task.Status = "failed"
+		task.Error = err.Error()
+	} else {
+		task.Status = "completed"
+		task.Result = result
+		task.Error = ""
+	}
+
+	task.NextRun = g.calcNextRun(task.Interval, now)
+
+	// 设置了告警条件的任务只在失败或条件满足时上报
+	if len(conds) == 0 || task.Status == "failed" {
+		g.recordChange(ChangeTypeUpdated, task.ID, oldStatus, task.Status)
+	}
+	if len(reasons) > 0 {
+		g.recordAlert(task.ID, strings.Join(reasons, "; "), result)
+	}
+	if task.Status == "completed" {
+		g.deliverDecisionLocked(task, delivered)
+		g.recordMetricsLocked(task, result)
+		if d := parseDecisionRequest(result); d != nil {
+			g.raiseDecisionLocked(task, d, oldStatus)
+		}
+	}
+
+	if g.memory != nil {
+		if cancelled {
+			g.memory.Write("task_cancelled", task.ID, "Periodic task run cancelled", nil)
 		} else {
-			task.Status = "pending"
+			g.memory.Write("task_executed", task.ID,
+				fmt.Sprintf("Periodic task executed: %s", result),
+				nil)
 		}
 	}
 }
 
+// recordRun 写入一次执行的历史记录
+func (g *PlanGenerator) recordRun(taskID string, started time.Time, out ExecResult, runErr error, stopped string) {
+	if g.store == nil {
+		return
+	}
+
+	ended := time.Now()
+	run := TaskRun{
+		RunID:            fmt.Sprintf("%d", started.UnixNano()),
+		TaskID:           taskID,
+		StartedAt:        started,
+		EndedAt:          ended,
+		DurationMs:       ended.Sub(started).Milliseconds(),
+		Status:           "completed",
+		Result:           out.Output,
+		Model:            out.Model,
+		PromptTokens:     out.PromptTokens,
+		CompletionTokens: out.CompletionTokens,
+	}
+	if stopped == stopCancelled {
+		run.Status = "cancelled"
+		run.Error = "cancelled by brain"
+	} else if stopped == stopTimeout {
+		run.Status = "timeout"
+		run.Error = "run exceeded timeout"
+	} else if runErr != nil {
+		run.Status = "failed"
+		run.Error = runErr.Error()
+	}
+
+	if err := g.store.AppendRun(run); err != nil {
+		log.Printf("Warning: Failed to record run for task %s: %v", taskID, err)
+	}
+}
+
 // recordChange 记录任务变化
 func (g *PlanGenerator) recordChange(changeType ChangeType, taskID, oldStatus, newStatus string) {
-	g.changesMu.Lock()
-	defer g.changesMu.Unlock()
-
-	g.changes = append(g.changes, TaskChange{
+	g.appendChange(TaskChange{
 		Type:      changeType,
 		TaskID:    taskID,
 		Timestamp: time.Now(),
@@ -254,14 +684,63 @@ func (g *PlanGenerator) recordChange(changeType ChangeType, taskID, oldStatus, n
 	})
 }
 
-// HasSignificantChanges 检查是否有显著变化（变化数 > 1）
+// appendChange 追加变化并通知监听者
+func (g *PlanGenerator) appendChange(change TaskChange) {
+	g.changesMu.Lock()
+	g.changes = append(g.changes, change)
+	listener := g.onChange
+	g.changesMu.Unlock()
+
+	if listener != nil {
+		listener(change)
+	}
+}
+
+// SetChangeListener 设置任务变化的监听函数（在记录变化时同步调用，不能阻塞）
+func (g *PlanGenerator) SetChangeListener(fn func(TaskChange)) {
+	g.changesMu.Lock()
+	defer g.changesMu.Unlock()
+	g.onChange = fn
+}
+
+// recordAlert 记录告警条件满足的变化
+func (g *PlanGenerator) recordAlert(taskID, reason, result string) {
+	g.appendChange(TaskChange{
+		Type:      ChangeTypeAlert,
+		TaskID:    taskID,
+		Timestamp: time.Now(),
+		NewStatus: "completed",
+		Result:    result,
+		Reason:    reason,
+	})
+
+	if g.memory != nil {
+		g.memory.Write("task_alert", taskID,
+			fmt.Sprintf("Alert: %s", reason),
+			map[string]string{"reason": reason, "result": result})
+	}
+}
+
+// HasSignificantChanges 检查是否有显著变化（有告警、恢复或决策请求，或变化数 > 1）
 func (g *PlanGenerator) HasSignificantChanges() bool {
 	g.changesMu.Lock()
 	defer g.changesMu.Unlock()
 
+	for _, change := range g.changes {
+		if change.Type == ChangeTypeAlert || change.Type == ChangeTypeRecovered || change.Type == ChangeTypeDecision {
+			return true
+		}
+	}
 	return len(g.changes) > 1
 }
 
+// SetJudge 设置 llm 告警条件使用的判断函数
+func (g *PlanGenerator) SetJudge(judge Judge) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.judge = judge
+}
+
 // GetAndClearChanges 获取并清空变化列表
 func (g *PlanGenerator) GetAndClearChanges() []TaskChange {
 	g.changesMu.Lock()
@@ -350,25 +829,10 @@ func (g *PlanGenerator) GetAllPlans() []*TaskPlan {
 	return plans
 }
 
-// RemoveCompletedTask 移除已完成的任务
-func (g *PlanGenerator) RemoveCompletedTask(id string) bool {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	if task, exists := g.onceTasks[id]; exists && task.Status == "completed" {
-		delete(g.onceTasks, id)
-		g.taskCount--
-
-		if g.memory != nil {
-			g.memory.Write("task_removed", id, "Completed task removed", nil)
-		}
-		return true
-	}
-	return false
-}
-
-// WriteReportToFile 将报告写入文件
-func WriteReportToFile(report map[string]interface{}, path string) error {
+// WriteReportToFile 将报告写入文件，extra 为追加在末尾的 Markdown 段落
+//
+// 先写临时文件再改名，读取报告文件的大脑不会读到写了一半的内容。
+func WriteReportToFile(report map[string]interface{}, path string, extra ...string) error {
 	content := fmt.Sprintf(`# Cerebellum Report
 Generated: %s
 
@@ -405,7 +869,15 @@ Generated: %s
 		content += fmt.Sprintf("- **%s**: %s\n", f.ID, f.Result)
 	}
 
-	return os.WriteFile(path, []byte(content), 0644)
+	for _, section := range extra {
+		content += "\n" + section
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
 }
 
 // ParseBrainTasks 从JSON解析大脑任务
@@ -437,107 +909,138 @@ func (g *PlanGenerator) GetRecentMemory(n int) ([]memory.MemoryEntry, error) {
 func (g *PlanGenerator) SaveTasks() error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	return g.saveLocked()
+}
 
-	if g.dataDir == "" {
+// saveLocked 保存全部任务快照（调用方需持有 g.mu）
+func (g *PlanGenerator) saveLocked() error {
+	if g.store == nil {
 		return nil
 	}
-
-	log.Printf("DEBUG SaveTasks: Saving %d periodic tasks", len(g.periodicTasks))
-
-	// 调试：检查所有周期性任务的 Interval
-	for id, task := range g.periodicTasks {
-		if task.Interval == "" {
-			log.Printf("WARNING: Task %s has empty Interval before save!", id)
-		}
-	}
-
-	// 确保目录存在
-	if err := os.MkdirAll(g.dataDir, 0755); err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
-	}
-
-	// 保存周期性任务
-	periodicFile := filepath.Join(g.dataDir, "periodic_tasks.json")
-	periodicData, err := json.MarshalIndent(g.periodicTasks, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal periodic tasks: %w", err)
-	}
-	log.Printf("DEBUG: Writing %d bytes to periodic_tasks.json", len(periodicData))
-	if err := os.WriteFile(periodicFile, periodicData, 0644); err != nil {
-		return fmt.Errorf("failed to write periodic tasks: %w", err)
-	}
-
-	// 保存一次性任务
-	onceFile := filepath.Join(g.dataDir, "once_tasks.json")
-	onceData, err := json.MarshalIndent(g.onceTasks, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal once tasks: %w", err)
-	}
-	if err := os.WriteFile(onceFile, onceData, 0644); err != nil {
-		return fmt.Errorf("failed to write once tasks: %w", err)
-	}
-
-	return nil
+	return g.store.Checkpoint(g.periodicTasks, g.onceTasks)
 }
 
-// LoadTasks 从磁盘加载任务状态
+// LoadTasks 从存储加载任务状态
 func (g *PlanGenerator) LoadTasks() error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	if g.dataDir == "" {
+	if g.store == nil {
 		return nil
 	}
 
-	// 加载周期性任务（合并到现有map中）
-	periodicFile := filepath.Join(g.dataDir, "periodic_tasks.json")
-	if _, err := os.Stat(periodicFile); err == nil {
-		data, err := os.ReadFile(periodicFile)
-		if err != nil {
-			return fmt.Errorf("failed to read periodic tasks: %w", err)
-		}
-		var loadedTasks map[string]*TaskPlan
-		if err := json.Unmarshal(data, &loadedTasks); err != nil {
-			return fmt.Errorf("failed to unmarshal periodic tasks: %w", err)
-		}
-		// 合并到现有map，保留新添加的任务
-		for id, task := range loadedTasks {
-			log.Printf("DEBUG LoadTasks: Loaded task %s, interval='%s'", id, task.Interval)
-			// 修复：确保 interval 有默认值
-			if task.Interval == "" {
-				log.Printf("WARNING: Loaded task %s has empty interval, setting to 1m", id)
-				task.Interval = "1m"
-			}
-			if _, exists := g.periodicTasks[id]; !exists {
-				g.periodicTasks[id] = task
-			}
+	periodic, once, report, err := g.store.Load()
+	if err != nil {
+		return err
+	}
+	g.recovery = report
+
+	// 合并到现有map，保留新添加的任务
+	for id, task := range periodic {
+		// 修复：确保 interval 有默认值
+		if task.Interval == "" {
+			log.Printf("WARNING: Loaded task %s has empty interval, setting to 1m", id)
+			task.Interval = "1m"
+		}
+		if task.Version == 0 {
+			task.Version = 1
+		}
+		if _, exists := g.periodicTasks[id]; !exists {
+			g.periodicTasks[id] = task
 		}
 	}
 
 	// 加载一次性任务
-	onceFile := filepath.Join(g.dataDir, "once_tasks.json")
-	if _, err := os.Stat(onceFile); err == nil {
-		data, err := os.ReadFile(onceFile)
-		if err != nil {
-			return fmt.Errorf("failed to read once tasks: %w", err)
-		}
-		if err := json.Unmarshal(data, &g.onceTasks); err != nil {
-			return fmt.Errorf("failed to unmarshal once tasks: %w", err)
+	for id, task := range once {
+		if task.Version == 0 {
+			task.Version = 1
 		}
+		g.onceTasks[id] = task
 	}
 
+	// 重启前仍在执行的任务已失去执行方，重新排队
+	g.recoverStaleTasks(time.Now())
+
 	// 更新任务计数
 	g.taskCount = len(g.periodicTasks) + len(g.onceTasks)
 	g.lastTaskCount = g.taskCount
 
+	log.Printf("Task recovery: %d from snapshot, %d journal records replayed, %d corrupt",
+		report.SnapshotTasks, report.JournalReplayed, report.JournalCorrupt)
+	for _, problem := range report.SnapshotProblems {
+		log.Printf("WARNING: Task snapshot problem: %s", problem)
+	}
+
+	// 有日志记录或快照损坏时立即生成新的快照
+	if report.JournalReplayed > 0 || report.JournalCorrupt > 0 || len(report.SnapshotProblems) > 0 {
+		if err := g.saveLocked(); err != nil {
+			return fmt.Errorf("failed to checkpoint recovered tasks: %w", err)
+		}
+	}
+
 	return nil
 }
 
-// SetDataDir 设置数据目录
+// SetDataDir 设置数据目录（使用文件存储）
 func (g *PlanGenerator) SetDataDir(dir string) {
+	store, err := NewFileStore(dir)
+	if err != nil {
+		log.Printf("Warning: Failed to initialize task store: %v", err)
+		return
+	}
+	g.SetStore(store)
+}
+
+// SetStore 设置任务持久化后端
+func (g *PlanGenerator) SetStore(store Store) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	g.dataDir = dir
+	g.store = store
+}
+
+// ListRuns 查询任务的执行历史
+func (g *PlanGenerator) ListRuns(id string, q RunQuery) ([]TaskRun, int, error) {
+	g.mu.Lock()
+	exists := g.findTask(id) != nil
+	store := g.store
+	g.mu.Unlock()
+
+	if !exists {
+		return nil, 0, ErrTaskNotFound
+	}
+	if store == nil {
+		return []TaskRun{}, 0, nil
+	}
+	return store.ListRuns(id, q)
+}
+
+// RunsBetween 返回所有现存任务在时间范围内开始的执行记录，按开始时间正序
+func (g *PlanGenerator) RunsBetween(since, until time.Time) ([]TaskRun, error) {
+	g.mu.Lock()
+	store := g.store
+	ids := make([]string, 0, len(g.periodicTasks)+len(g.onceTasks))
+	for id := range g.periodicTasks {
+		ids = append(ids, id)
+	}
+	for id := range g.onceTasks {
+		ids = append(ids, id)
+	}
+	g.mu.Unlock()
+
+	if store == nil {
+		return []TaskRun{}, nil
+	}
+
+	runs := make([]TaskRun, 0)
+	for _, id := range ids {
+		list, _, err := store.ListRuns(id, RunQuery{Since: since, Until: until})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list runs of %s: %w", id, err)
+		}
+		runs = append(runs, list...)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.Before(runs[j].StartedAt) })
+	return runs, nil
 }
 
 // GetPendingTasks 获取所有待处理任务
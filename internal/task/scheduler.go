@@ -0,0 +1,174 @@
+package task
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultOwner 未指定 owner 的任务归属
+const DefaultOwner = "default"
+
+// SchedulerLimits 执行并发限制
+type SchedulerLimits struct {
+	MaxConcurrency   int            // 同时执行的任务总数上限
+	OwnerConcurrency int            // 每个 owner 同时执行的任务数上限
+	OwnerLimits      map[string]int // 按 owner 覆盖 OwnerConcurrency
+}
+
+// scheduledRun 排好顺序的一次执行，owner 与类型在持锁时取出
+type scheduledRun struct {
+	task     *TaskPlan
+	owner    string
+	periodic bool
+}
+
+// ownerOf 返回任务归属，空值视为 DefaultOwner
+func ownerOf(task *TaskPlan) string {
+	if task.Owner == "" {
+		return DefaultOwner
+	}
+	return task.Owner
+}
+
+// SetSchedulerLimits 设置执行并发限制，非正数保持默认值
+func (g *PlanGenerator) SetSchedulerLimits(limits SchedulerLimits) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if limits.MaxConcurrency > 0 {
+		g.limits.MaxConcurrency = limits.MaxConcurrency
+	}
+	if limits.OwnerConcurrency > 0 {
+		g.limits.OwnerConcurrency = limits.OwnerConcurrency
+	}
+	g.limits.OwnerLimits = make(map[string]int, len(limits.OwnerLimits))
+	for owner, n := range limits.OwnerLimits {
+		if n > 0 {
+			g.limits.OwnerLimits[owner] = n
+		}
+	}
+}
+
+// SetMaxTasks 设置任务数上限（0 表示不限），只限制新建任务
+func (g *PlanGenerator) SetMaxTasks(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.maxTasks = n
+}
+
+// ownerLimit 返回 owner 的并发上限（调用方需持有 g.mu）
+func (g *PlanGenerator) ownerLimit(owner string) int {
+	if n, ok := g.limits.OwnerLimits[owner]; ok {
+		return n
+	}
+	return g.limits.OwnerConcurrency
+}
+
+// dueTime 任务进入待执行状态的时间，用于同优先级任务排序
+func dueTime(task *TaskPlan) time.Time {
+	if task.Type == TaskTypeOnce {
+		return task.CreatedAt
+	}
+	return task.NextRun
+}
+
+// scheduleOrder 决定本轮到期任务的执行顺序（调用方需持有 g.mu）
+//
+// 每个 owner 的任务按优先级从高到低、到期时间从早到晚排队；各 owner 轮流出队，
+// 每一轮中各 owner 的队首再按优先级排序。这样高优先级任务先执行，
+// 但任一 owner 任务再多也只能在每轮占一个位置，不会饿死其他 owner。
+func scheduleOrder(due []*TaskPlan) []scheduledRun {
+	queues := make(map[string][]*TaskPlan)
+	var owners []string
+	for _, task := range due {
+		owner := ownerOf(task)
+		if _, ok := queues[owner]; !ok {
+			owners = append(owners, owner)
+		}
+		queues[owner] = append(queues[owner], task)
+	}
+
+	for _, queue := range queues {
+		sort.SliceStable(queue, func(i, j int) bool {
+			if queue[i].Priority != queue[j].Priority {
+				return queue[i].Priority > queue[j].Priority
+			}
+			ti, tj := dueTime(queue[i]), dueTime(queue[j])
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+			return queue[i].ID < queue[j].ID
+		})
+	}
+	sort.Strings(owners)
+
+	ordered := make([]scheduledRun, 0, len(due))
+	for round := 0; len(ordered) < len(due); round++ {
+		var heads []*TaskPlan
+		for _, owner := range owners {
+			if round < len(queues[owner]) {
+				heads = append(heads, queues[owner][round])
+			}
+		}
+		sort.SliceStable(heads, func(i, j int) bool {
+			return heads[i].Priority > heads[j].Priority
+		})
+		for _, task := range heads {
+			ordered = append(ordered, scheduledRun{
+				task:     task,
+				owner:    ownerOf(task),
+				periodic: task.Type == TaskTypePeriodic,
+			})
+		}
+	}
+	return ordered
+}
+
+// dispatch 按顺序并发执行任务，遵守总并发和每个 owner 的并发上限
+//
+// 某个 owner 达到上限时跳过其任务，让后面其他 owner 的任务先执行。
+func (g *PlanGenerator) dispatch(ordered []scheduledRun, now time.Time, executor Executor) {
+	g.mu.Lock()
+	maxConcurrency := g.limits.MaxConcurrency
+	limits := make(map[string]int)
+	for _, run := range ordered {
+		limits[run.owner] = g.ownerLimit(run.owner)
+	}
+	g.mu.Unlock()
+
+	done := make(chan string)
+	active := 0
+	perOwner := make(map[string]int)
+	queue := ordered
+
+	for len(queue) > 0 || active > 0 {
+		next := -1
+		if active < maxConcurrency {
+			for i, run := range queue {
+				if perOwner[run.owner] < limits[run.owner] {
+					next = i
+					break
+				}
+			}
+		}
+		if next < 0 {
+			perOwner[<-done]--
+			active--
+			continue
+		}
+
+		run := queue[next]
+		queue = append(queue[:next:next], queue[next+1:]...)
+		perOwner[run.owner]++
+		active++
+
+		go func() {
+			if run.periodic {
+				g.runPeriodicTask(run.task, now, executor)
+			} else {
+				g.runOnceTask(run.task, now, executor)
+			}
+			done <- run.owner
+		}()
+	}
+}
@@ -0,0 +1,196 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store 任务与执行历史的持久化后端
+type Store interface {
+	// Load 读取全部任务，返回周期任务、一次性任务和恢复报告
+	Load() (periodic, once map[string]*TaskPlan, report *RecoveryReport, err error)
+	// Put 持久化单个任务的最新状态
+	Put(task *TaskPlan) error
+	// Delete 删除单个任务
+	Delete(id string) error
+	// Checkpoint 保存全部任务的完整快照
+	Checkpoint(periodic, once map[string]*TaskPlan) error
+
+	AppendRun(run TaskRun) error
+	ListRuns(taskID string, q RunQuery) ([]TaskRun, int, error)
+	RemoveRuns(taskID string) error
+}
+
+// FileStore 基于 JSON 快照、变更日志和 JSONL 执行历史的文件存储
+type FileStore struct {
+	dir     string
+	journal *taskJournal
+	history *RunHistory
+	mu      sync.Mutex
+}
+
+// NewFileStore 创建文件存储
+func NewFileStore(dir string) (*FileStore, error) {
+	history, err := NewRunHistory(filepath.Join(dir, "runs"))
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, history: history}, nil
+}
+
+// Load 先读快照，再重放上次快照之后的变更日志
+func (s *FileStore) Load() (map[string]*TaskPlan, map[string]*TaskPlan, *RecoveryReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &RecoveryReport{LoadedAt: time.Now()}
+
+	periodic, err := readSnapshot(filepath.Join(s.dir, "periodic_tasks.json"))
+	if err != nil {
+		report.SnapshotProblems = append(report.SnapshotProblems, err.Error())
+	}
+	once, err := readSnapshot(filepath.Join(s.dir, "once_tasks.json"))
+	if err != nil {
+		report.SnapshotProblems = append(report.SnapshotProblems, err.Error())
+	}
+	if periodic == nil {
+		periodic = make(map[string]*TaskPlan)
+	}
+	if once == nil {
+		once = make(map[string]*TaskPlan)
+	}
+	report.SnapshotTasks = len(periodic) + len(once)
+
+	journalPath := filepath.Join(s.dir, "tasks.journal")
+	lastSeq, err := replayJournal(journalPath, report, func(rec journalRecord) {
+		delete(periodic, rec.TaskID)
+		delete(once, rec.TaskID)
+		if rec.Op != "put" {
+			return
+		}
+		if rec.Task.Type == TaskTypePeriodic {
+			periodic[rec.TaskID] = rec.Task
+		} else {
+			once[rec.TaskID] = rec.Task
+		}
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if s.journal == nil {
+		if s.journal, err = openJournal(journalPath, lastSeq); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return periodic, once, report, nil
+}
+
+// Put 追加一条 put 日志
+func (s *FileStore) Put(task *TaskPlan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.append("put", task.ID, task)
+}
+
+// Delete 追加一条 delete 日志
+func (s *FileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.append("delete", id, nil)
+}
+
+// Checkpoint 原子写入任务快照并清空变更日志
+func (s *FileStore) Checkpoint(periodic, once map[string]*TaskPlan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 检查所有周期性任务的 Interval
+	for id, task := range periodic {
+		if task.Interval == "" {
+			log.Printf("WARNING: Task %s has empty Interval before save!", id)
+		}
+	}
+
+	// 确保目录存在
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	// 保存周期性任务
+	periodicFile := filepath.Join(s.dir, "periodic_tasks.json")
+	periodicData, err := json.MarshalIndent(periodic, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal periodic tasks: %w", err)
+	}
+	if err := writeFileAtomic(periodicFile, periodicData); err != nil {
+		return fmt.Errorf("failed to write periodic tasks: %w", err)
+	}
+
+	// 保存一次性任务
+	onceFile := filepath.Join(s.dir, "once_tasks.json")
+	onceData, err := json.MarshalIndent(once, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal once tasks: %w", err)
+	}
+	if err := writeFileAtomic(onceFile, onceData); err != nil {
+		return fmt.Errorf("failed to write once tasks: %w", err)
+	}
+
+	// 快照已持久化，之前的变更日志不再需要
+	if s.journal != nil {
+		if err := s.journal.reset(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AppendRun 写入一次执行记录
+func (s *FileStore) AppendRun(run TaskRun) error {
+	return s.history.Append(run)
+}
+
+// ListRuns 查询执行历史
+func (s *FileStore) ListRuns(taskID string, q RunQuery) ([]TaskRun, int, error) {
+	return s.history.List(taskID, q)
+}
+
+// RemoveRuns 删除任务的执行历史
+func (s *FileStore) RemoveRuns(taskID string) error {
+	return s.history.Remove(taskID)
+}
+
+// readSnapshot 读取任务快照文件；文件损坏时将其改名保留，以便人工排查
+func readSnapshot(path string) (map[string]*TaskPlan, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	var tasks map[string]*TaskPlan
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		corruptPath := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102_150405"))
+		os.Rename(path, corruptPath)
+		return nil, fmt.Errorf("failed to unmarshal %s (moved to %s): %w", filepath.Base(path), filepath.Base(corruptPath), err)
+	}
+	return tasks, nil
+}